@@ -0,0 +1,104 @@
+package pcap
+
+import (
+	"sync"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// appDecoderKey identifies a registered application layer decoder by transport protocol and
+// port.
+type appDecoderKey struct {
+	proto gopacket.LayerType
+	port  uint16
+}
+
+var (
+	appDecodersMu sync.RWMutex
+	appDecoders   = make(map[appDecoderKey]gopacket.Decoder)
+)
+
+// RegisterAppDecoder registers dec to decode the application layer payload of packets whose
+// transport protocol is proto (layers.LayerTypeTCP, layers.LayerTypeUDP or layers.LayerTypeSCTP)
+// and whose source or destination port is port. ParsePacket consults the registry and, when a
+// decoder matches,
+// chains it onto the payload the way a MoldUDP64 decoder feeds into an Itto decoder over a
+// gopacket.PacketBuilder, making the decoded layers available through
+// PacketIndicator.AppLayers.
+//
+// RegisterAppDecoder is typically called from an init function and is safe for concurrent use.
+// Registering a second decoder for the same proto and port replaces the first.
+func RegisterAppDecoder(port uint16, proto gopacket.LayerType, dec gopacket.Decoder) {
+	appDecodersMu.Lock()
+	defer appDecodersMu.Unlock()
+
+	appDecoders[appDecoderKey{proto: proto, port: port}] = dec
+}
+
+func lookupAppDecoder(proto gopacket.LayerType, port uint16) (gopacket.Decoder, bool) {
+	appDecodersMu.RLock()
+	defer appDecodersMu.RUnlock()
+
+	dec, ok := appDecoders[appDecoderKey{proto: proto, port: port}]
+
+	return dec, ok
+}
+
+// runAppDecoder looks up a decoder registered for proto and either of srcPort/dstPort and, if
+// found, chains it onto packet so the decoded layers are appended to packet's layer list. It
+// returns the layers the decoder added, or nil if no decoder matched or decoding failed.
+func runAppDecoder(packet gopacket.Packet, proto gopacket.LayerType, srcPort, dstPort uint16, payload []byte) []gopacket.Layer {
+	if len(payload) == 0 {
+		return nil
+	}
+
+	dec, ok := lookupAppDecoder(proto, dstPort)
+	if !ok {
+		dec, ok = lookupAppDecoder(proto, srcPort)
+		if !ok {
+			return nil
+		}
+	}
+
+	builder, ok := packet.(gopacket.PacketBuilder)
+	if !ok {
+		return nil
+	}
+
+	before := len(packet.Layers())
+
+	err := dec.Decode(payload, builder)
+	if err != nil {
+		return nil
+	}
+
+	after := packet.Layers()
+	if len(after) <= before {
+		return nil
+	}
+
+	return after[before:]
+}
+
+func init() {
+	dnsDecoder := gopacket.DecodeFunc(decodeDNS)
+
+	RegisterAppDecoder(53, layers.LayerTypeUDP, dnsDecoder)
+	RegisterAppDecoder(53, layers.LayerTypeTCP, dnsDecoder)
+}
+
+// decodeDNS decodes data as a DNS message and adds it as a layer, so that ports 53 get
+// PacketIndicator.DNS() for free without upstream components re-parsing the payload.
+func decodeDNS(data []byte, p gopacket.PacketBuilder) error {
+	dns := &layers.DNS{}
+
+	err := dns.DecodeFromBytes(data, p)
+	if err != nil {
+		return err
+	}
+
+	p.AddLayer(dns)
+
+	return p.NextDecoder(gopacket.LayerTypePayload)
+}
@@ -0,0 +1,71 @@
+package pcap
+
+import (
+	"net"
+	"testing"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+func TestParsePacket_DecodesDNSOverUDP53(t *testing.T) {
+	eth := layers.Ethernet{
+		SrcMAC:       net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x55},
+		DstMAC:       net.HardwareAddr{0x00, 0x66, 0x77, 0x88, 0x99, 0xaa},
+		EthernetType: layers.EthernetTypeIPv4,
+	}
+	ip4 := layers.IPv4{
+		Version:  4,
+		TTL:      64,
+		SrcIP:    net.IPv4(192, 168, 1, 1),
+		DstIP:    net.IPv4(8, 8, 8, 8),
+		Protocol: layers.IPProtocolUDP,
+	}
+	udp := layers.UDP{
+		SrcPort: 40000,
+		DstPort: 53,
+	}
+	err := udp.SetNetworkLayerForChecksum(&ip4)
+	if err != nil {
+		t.Fatalf("set network layer for checksum: %v", err)
+	}
+
+	dns := layers.DNS{
+		ID:     0x1234,
+		QR:     false,
+		OpCode: layers.DNSOpCodeQuery,
+		Questions: []layers.DNSQuestion{
+			{
+				Name:  []byte("example.com"),
+				Type:  layers.DNSTypeA,
+				Class: layers.DNSClassIN,
+			},
+		},
+	}
+
+	buf := gopacket.NewSerializeBuffer()
+	opts := gopacket.SerializeOptions{ComputeChecksums: true, FixLengths: true}
+
+	err = gopacket.SerializeLayers(buf, opts, &eth, &ip4, &udp, &dns)
+	if err != nil {
+		t.Fatalf("serialize layers: %v", err)
+	}
+
+	packet := gopacket.NewPacket(buf.Bytes(), layers.LayerTypeEthernet, gopacket.Default)
+
+	indicator, err := ParsePacket(packet)
+	if err != nil {
+		t.Fatalf("parse packet: %v", err)
+	}
+
+	got := indicator.DNS()
+	if got == nil {
+		t.Fatalf("expected DNS() to return a decoded layer")
+	}
+	if got.ID != 0x1234 {
+		t.Fatalf("DNS ID = %x, want %x", got.ID, 0x1234)
+	}
+	if len(got.Questions) != 1 || string(got.Questions[0].Name) != "example.com" {
+		t.Fatalf("unexpected DNS questions: %+v", got.Questions)
+	}
+}
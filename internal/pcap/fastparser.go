@@ -0,0 +1,163 @@
+package pcap
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// FastParser is a low-allocation fast path for parsing packets captured from a live
+// interface. Unlike ParsePacket, which builds a gopacket.Packet and lazily decodes it into
+// independently allocated layers, FastParser wraps a gopacket.DecodingLayerParser pre-wired
+// with the layers ikago understands and points the returned PacketIndicator directly at the
+// parser's own layer structs, which are reused on every call. Each Parse still allocates the
+// returned PacketIndicator, and an ICMP indicator for ICMP packets.
+//
+// A FastParser is not safe for concurrent use; callers on the hot path should pull one from
+// a FastParserPool per packet and return it once they are done with the resulting indicator.
+type FastParser struct {
+	parser  *gopacket.DecodingLayerParser
+	decoded []gopacket.LayerType
+
+	eth      layers.Ethernet
+	loopback layers.Loopback
+	arp      layers.ARP
+	ip4      layers.IPv4
+	ip6      layers.IPv6
+	ip6frag  layers.IPv6Fragment
+	tcp      layers.TCP
+	udp      layers.UDP
+	sctp     layers.SCTP
+	icmp4    layers.ICMPv4
+	icmp6    layers.ICMPv6
+	payload  gopacket.Payload
+}
+
+// NewFastParser returns a FastParser whose DecodingLayerParser starts decoding from
+// firstLayerType, which should match the link type reported by the live handle the packets
+// are read from (typically layers.LayerTypeEthernet or layers.LayerTypeLoopback).
+func NewFastParser(firstLayerType gopacket.LayerType) *FastParser {
+	p := &FastParser{}
+
+	p.parser = gopacket.NewDecodingLayerParser(firstLayerType,
+		&p.eth, &p.loopback, &p.arp, &p.ip4, &p.ip6, &p.ip6frag,
+		&p.tcp, &p.udp, &p.sctp, &p.icmp4, &p.icmp6, &p.payload)
+	p.parser.IgnoreUnsupported = true
+
+	return p
+}
+
+// Parse parses contents and returns a packet indicator pointing at the parser's own layer
+// structs. The returned indicator, and any layer accessed through it, is only valid until the
+// next call to Parse on the same FastParser. If the link layer cannot be decoded by the fast
+// path (for example an ethertype FastParser is not wired for, such as 802.1Q/QinQ tagged
+// frames), Parse falls back to the slow ParsePacket path.
+func (p *FastParser) Parse(contents []byte) (*PacketIndicator, error) {
+	err := p.parser.DecodeLayers(contents, &p.decoded)
+	if err == nil {
+		indicator, berr := p.buildIndicator(contents)
+		if berr == nil {
+			return indicator, nil
+		}
+	}
+
+	packet, rerr := ParseRawPacket(contents)
+	if rerr != nil {
+		if err != nil {
+			return nil, fmt.Errorf("decode layers: %w", err)
+		}
+
+		return nil, errors.New("decode layers: missing network layer")
+	}
+
+	return ParsePacket(packet)
+}
+
+// buildIndicator assembles a PacketIndicator from the layers DecodeLayers most recently
+// populated. With IgnoreUnsupported set, DecodeLayers returns a nil error even when it gives up
+// on a layer it has no decoder for (such as an 802.1Q tag), so the absence of a network layer
+// here is the only signal that the fast path could not handle contents.
+func (p *FastParser) buildIndicator(contents []byte) (*PacketIndicator, error) {
+	indicator := &PacketIndicator{data: contents}
+
+	for _, t := range p.decoded {
+		switch t {
+		case layers.LayerTypeLoopback:
+			indicator.linkLayer = &p.loopback
+		case layers.LayerTypeEthernet:
+			indicator.linkLayer = &p.eth
+		case layers.LayerTypeARP:
+			indicator.networkLayer = &p.arp
+		case layers.LayerTypeIPv4:
+			indicator.networkLayer = &p.ip4
+		case layers.LayerTypeIPv6:
+			indicator.networkLayer = &p.ip6
+		case layers.LayerTypeIPv6Fragment:
+			indicator.ipv6FragmentLayer = &p.ip6frag
+		case layers.LayerTypeTCP:
+			indicator.transportLayer = &p.tcp
+		case layers.LayerTypeUDP:
+			indicator.transportLayer = &p.udp
+		case layers.LayerTypeSCTP:
+			indicator.transportLayer = &p.sctp
+		case layers.LayerTypeICMPv4:
+			indicator.transportLayer = &p.icmp4
+
+			icmpv4Indicator, err := ParseICMPv4Layer(&p.icmp4)
+			if err != nil {
+				return nil, fmt.Errorf("parse icmpv4 layer: %w", err)
+			}
+
+			indicator.icmpv4Indicator = icmpv4Indicator
+		case layers.LayerTypeICMPv6:
+			indicator.transportLayer = &p.icmp6
+
+			icmpv6Indicator, err := ParseICMPv6Layer(&p.icmp6)
+			if err != nil {
+				return nil, fmt.Errorf("parse icmpv6 layer: %w", err)
+			}
+
+			indicator.icmpv6Indicator = icmpv6Indicator
+		case gopacket.LayerTypePayload:
+			indicator.applicationLayer = &p.payload
+		}
+	}
+
+	if indicator.networkLayer == nil {
+		return nil, errors.New("missing network layer")
+	}
+
+	return indicator, nil
+}
+
+// FastParserPool pools FastParsers keyed to a single link layer type so RawConn.ReadPacket can
+// borrow one per packet instead of allocating a DecodingLayerParser from scratch.
+type FastParserPool struct {
+	firstLayerType gopacket.LayerType
+	pool           sync.Pool
+}
+
+// NewFastParserPool returns a FastParserPool whose FastParsers start decoding from
+// firstLayerType.
+func NewFastParserPool(firstLayerType gopacket.LayerType) *FastParserPool {
+	p := &FastParserPool{firstLayerType: firstLayerType}
+
+	p.pool.New = func() interface{} {
+		return NewFastParser(p.firstLayerType)
+	}
+
+	return p
+}
+
+// Get returns a FastParser from the pool, creating one if the pool is empty.
+func (p *FastParserPool) Get() *FastParser {
+	return p.pool.Get().(*FastParser)
+}
+
+// Put returns a FastParser to the pool for reuse.
+func (p *FastParserPool) Put(parser *FastParser) {
+	p.pool.Put(parser)
+}
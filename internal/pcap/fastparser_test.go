@@ -0,0 +1,142 @@
+package pcap
+
+import (
+	"net"
+	"testing"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// buildBenchPacket hand-serializes an Ethernet+IPv4+TCP frame for the benchmarks below.
+func buildBenchPacket(tb testing.TB) []byte {
+	eth := layers.Ethernet{
+		SrcMAC:       net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x55},
+		DstMAC:       net.HardwareAddr{0x00, 0x66, 0x77, 0x88, 0x99, 0xaa},
+		EthernetType: layers.EthernetTypeIPv4,
+	}
+	ip4 := layers.IPv4{
+		Version:  4,
+		TTL:      64,
+		Id:       1,
+		SrcIP:    net.IPv4(192, 168, 1, 1),
+		DstIP:    net.IPv4(192, 168, 1, 2),
+		Protocol: layers.IPProtocolTCP,
+	}
+	tcp := layers.TCP{
+		SrcPort: 12345,
+		DstPort: 80,
+		SYN:     true,
+	}
+	err := tcp.SetNetworkLayerForChecksum(&ip4)
+	if err != nil {
+		tb.Fatalf("set network layer for checksum: %v", err)
+	}
+
+	buf := gopacket.NewSerializeBuffer()
+	opts := gopacket.SerializeOptions{ComputeChecksums: true, FixLengths: true}
+	err = gopacket.SerializeLayers(buf, opts, &eth, &ip4, &tcp, gopacket.Payload("ping"))
+	if err != nil {
+		tb.Fatalf("serialize layers: %v", err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestFastParser_MatchesParsePacket(t *testing.T) {
+	contents := buildBenchPacket(t)
+
+	slow, err := ParsePacket(gopacket.NewPacket(contents, layers.LayerTypeEthernet, gopacket.Default))
+	if err != nil {
+		t.Fatalf("parse packet: %v", err)
+	}
+
+	fast, err := NewFastParser(layers.LayerTypeEthernet).Parse(contents)
+	if err != nil {
+		t.Fatalf("fast parse: %v", err)
+	}
+
+	if !fast.SrcIP().Equal(slow.SrcIP()) || !fast.DstIP().Equal(slow.DstIP()) {
+		t.Fatalf("FastParser src/dst = %s/%s, want %s/%s", fast.SrcIP(), fast.DstIP(), slow.SrcIP(), slow.DstIP())
+	}
+	if fast.SrcPort() != slow.SrcPort() || fast.DstPort() != slow.DstPort() {
+		t.Fatalf("FastParser ports = %d/%d, want %d/%d", fast.SrcPort(), fast.DstPort(), slow.SrcPort(), slow.DstPort())
+	}
+}
+
+func TestFastParser_FallsBackOnDot1Q(t *testing.T) {
+	eth := layers.Ethernet{
+		SrcMAC:       net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x55},
+		DstMAC:       net.HardwareAddr{0x00, 0x66, 0x77, 0x88, 0x99, 0xaa},
+		EthernetType: layers.EthernetTypeDot1Q,
+	}
+	dot1q := layers.Dot1Q{
+		VLANIdentifier: 42,
+		Type:           layers.EthernetTypeIPv4,
+	}
+	ip4 := layers.IPv4{
+		Version:  4,
+		TTL:      64,
+		SrcIP:    net.IPv4(192, 168, 1, 1),
+		DstIP:    net.IPv4(192, 168, 1, 2),
+		Protocol: layers.IPProtocolTCP,
+	}
+	tcp := layers.TCP{SrcPort: 1234, DstPort: 80, SYN: true}
+
+	err := tcp.SetNetworkLayerForChecksum(&ip4)
+	if err != nil {
+		t.Fatalf("set network layer for checksum: %v", err)
+	}
+
+	buf := gopacket.NewSerializeBuffer()
+	opts := gopacket.SerializeOptions{ComputeChecksums: true, FixLengths: true}
+
+	err = gopacket.SerializeLayers(buf, opts, &eth, &dot1q, &ip4, &tcp)
+	if err != nil {
+		t.Fatalf("serialize layers: %v", err)
+	}
+
+	indicator, err := NewFastParser(layers.LayerTypeEthernet).Parse(buf.Bytes())
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	vlan := indicator.VLAN()
+	if len(vlan) != 1 || vlan[0] != 42 {
+		t.Fatalf("VLAN() = %v, want [42]", vlan)
+	}
+	if indicator.TCPLayer() == nil {
+		t.Fatalf("expected TCP layer to be resolved through the fallback path")
+	}
+}
+
+func BenchmarkParsePacket(b *testing.B) {
+	contents := buildBenchPacket(b)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		packet := gopacket.NewPacket(contents, layers.LayerTypeEthernet, gopacket.Default)
+
+		_, err := ParsePacket(packet)
+		if err != nil {
+			b.Fatalf("parse packet: %v", err)
+		}
+	}
+}
+
+func BenchmarkFastParser_Parse(b *testing.B) {
+	contents := buildBenchPacket(b)
+	parser := NewFastParser(layers.LayerTypeEthernet)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		_, err := parser.Parse(contents)
+		if err != nil {
+			b.Fatalf("parse: %v", err)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package pcap
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// ICMPv6Indicator indicates an ICMPv6 layer, mirroring ICMPv4Indicator. An ICMPv6 packet is
+// either a query (echo request/reply, carrying an identifier and sequence number like ICMPv4),
+// a Neighbor Discovery message (router/neighbor solicitation, advertisement, or redirect), or an
+// error referencing an embedded packet (destination unreachable, packet too big, time exceeded,
+// or parameter problem).
+type ICMPv6Indicator struct {
+	layer        *layers.ICMPv6
+	echo         *layers.ICMPv6Echo
+	embIndicator *PacketIndicator
+}
+
+// ICMPv6Layer returns the ICMPv6 layer.
+func (indicator *ICMPv6Indicator) ICMPv6Layer() *layers.ICMPv6 {
+	return indicator.layer
+}
+
+// IsQuery returns if the ICMPv6 layer is an echo request or reply.
+func (indicator *ICMPv6Indicator) IsQuery() bool {
+	switch indicator.layer.TypeCode.Type() {
+	case layers.ICMPv6TypeEchoRequest, layers.ICMPv6TypeEchoReply:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsError returns if the ICMPv6 layer is an error referencing an embedded packet
+// (destination unreachable, packet too big, time exceeded, or parameter problem). It is false
+// for both queries and Neighbor Discovery messages, neither of which carry an embedded packet.
+func (indicator *ICMPv6Indicator) IsError() bool {
+	return indicator.embIndicator != nil
+}
+
+// Id returns the Id of an ICMPv6 echo request or reply.
+func (indicator *ICMPv6Indicator) Id() uint16 {
+	return indicator.echo.Identifier
+}
+
+// Seq returns the sequence number of an ICMPv6 echo request or reply.
+func (indicator *ICMPv6Indicator) Seq() uint16 {
+	return indicator.echo.SeqNumber
+}
+
+// EmbIndicator returns the packet indicator embedded in an ICMPv6 error.
+func (indicator *ICMPv6Indicator) EmbIndicator() *PacketIndicator {
+	return indicator.embIndicator
+}
+
+// EmbSrc returns the source of the packet embedded in an ICMPv6 error.
+func (indicator *ICMPv6Indicator) EmbSrc() net.Addr {
+	return indicator.embIndicator.Src()
+}
+
+// EmbDst returns the destination of the packet embedded in an ICMPv6 error.
+func (indicator *ICMPv6Indicator) EmbDst() net.Addr {
+	return indicator.embIndicator.Dst()
+}
+
+// EmbTransportLayer returns the transport layer of the packet embedded in an ICMPv6 error.
+func (indicator *ICMPv6Indicator) EmbTransportLayer() gopacket.Layer {
+	return indicator.embIndicator.TransportLayer()
+}
+
+// ParseICMPv6Layer parses an ICMPv6 layer and returns an ICMPv6 indicator.
+func ParseICMPv6Layer(layer *layers.ICMPv6) (*ICMPv6Indicator, error) {
+	indicator := &ICMPv6Indicator{layer: layer}
+
+	switch t := layer.TypeCode.Type(); t {
+	case layers.ICMPv6TypeEchoRequest, layers.ICMPv6TypeEchoReply:
+		echo := &layers.ICMPv6Echo{}
+
+		err := echo.DecodeFromBytes(layer.LayerPayload(), gopacket.NilDecodeFeedback)
+		if err != nil {
+			return nil, fmt.Errorf("decode icmpv6 echo: %w", err)
+		}
+
+		indicator.echo = echo
+	case layers.ICMPv6TypeDestinationUnreachable, layers.ICMPv6TypePacketTooBig,
+		layers.ICMPv6TypeTimeExceeded, layers.ICMPv6TypeParameterProblem:
+		// The first 4 bytes of LayerPayload are the message body field (unused/MTU/pointer,
+		// depending on type), which gopacket's ICMPv6 layer does not consume; the embedded
+		// packet starts after it.
+		embIndicator, err := ParseEmbPacket(layer.LayerPayload()[4:])
+		if err != nil {
+			return nil, fmt.Errorf("parse embedded packet: %w", err)
+		}
+
+		indicator.embIndicator = embIndicator
+	case layers.ICMPv6TypeRouterSolicitation, layers.ICMPv6TypeRouterAdvertisement,
+		layers.ICMPv6TypeNeighborSolicitation, layers.ICMPv6TypeNeighborAdvertisement,
+		layers.ICMPv6TypeRedirect:
+		// Neighbor Discovery options are passed through on the ICMPv6 layer's payload as-is;
+		// there is no embedded packet or echo id/seq to extract.
+	default:
+		return nil, fmt.Errorf("icmpv6 type %d not support", t)
+	}
+
+	return indicator, nil
+}
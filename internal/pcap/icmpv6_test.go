@@ -0,0 +1,230 @@
+package pcap
+
+import (
+	"net"
+	"testing"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+func TestParsePacket_ICMPv6Echo(t *testing.T) {
+	eth := layers.Ethernet{
+		SrcMAC:       net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x55},
+		DstMAC:       net.HardwareAddr{0x00, 0x66, 0x77, 0x88, 0x99, 0xaa},
+		EthernetType: layers.EthernetTypeIPv6,
+	}
+	ip6 := layers.IPv6{
+		Version:    6,
+		HopLimit:   64,
+		SrcIP:      net.ParseIP("2001:db8::1"),
+		DstIP:      net.ParseIP("2001:db8::2"),
+		NextHeader: layers.IPProtocolICMPv6,
+	}
+	icmp6 := layers.ICMPv6{
+		TypeCode: layers.CreateICMPv6TypeCode(layers.ICMPv6TypeEchoRequest, 0),
+	}
+	echo := layers.ICMPv6Echo{
+		Identifier: 0x1234,
+		SeqNumber:  7,
+	}
+
+	err := icmp6.SetNetworkLayerForChecksum(&ip6)
+	if err != nil {
+		t.Fatalf("set network layer for checksum: %v", err)
+	}
+
+	buf := gopacket.NewSerializeBuffer()
+	opts := gopacket.SerializeOptions{ComputeChecksums: true, FixLengths: true}
+
+	err = gopacket.SerializeLayers(buf, opts, &eth, &ip6, &icmp6, &echo, gopacket.Payload("hello"))
+	if err != nil {
+		t.Fatalf("serialize layers: %v", err)
+	}
+
+	packet := gopacket.NewPacket(buf.Bytes(), layers.LayerTypeEthernet, gopacket.Default)
+
+	indicator, err := ParsePacket(packet)
+	if err != nil {
+		t.Fatalf("parse packet: %v", err)
+	}
+
+	icmpv6Indicator := indicator.ICMPv6Indicator()
+	if icmpv6Indicator == nil {
+		t.Fatalf("expected an ICMPv6 indicator")
+	}
+	if !icmpv6Indicator.IsQuery() {
+		t.Fatalf("expected an echo request to be a query")
+	}
+	if icmpv6Indicator.Id() != 0x1234 {
+		t.Fatalf("Id() = %#x, want 0x1234", icmpv6Indicator.Id())
+	}
+	if icmpv6Indicator.Seq() != 7 {
+		t.Fatalf("Seq() = %d, want 7", icmpv6Indicator.Seq())
+	}
+}
+
+func TestParsePacket_ICMPv6DestinationUnreachable(t *testing.T) {
+	eth := layers.Ethernet{
+		SrcMAC:       net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x55},
+		DstMAC:       net.HardwareAddr{0x00, 0x66, 0x77, 0x88, 0x99, 0xaa},
+		EthernetType: layers.EthernetTypeIPv6,
+	}
+	ip6 := layers.IPv6{
+		Version:    6,
+		HopLimit:   64,
+		SrcIP:      net.ParseIP("2001:db8::1"),
+		DstIP:      net.ParseIP("2001:db8::2"),
+		NextHeader: layers.IPProtocolICMPv6,
+	}
+	icmp6 := layers.ICMPv6{
+		TypeCode: layers.CreateICMPv6TypeCode(layers.ICMPv6TypeDestinationUnreachable, 0),
+	}
+
+	// The embedded packet that supposedly triggered the error.
+	embIP6 := layers.IPv6{
+		Version:    6,
+		HopLimit:   64,
+		SrcIP:      net.ParseIP("2001:db8::2"),
+		DstIP:      net.ParseIP("2001:db8::1"),
+		NextHeader: layers.IPProtocolUDP,
+	}
+	embUDP := layers.UDP{SrcPort: 1111, DstPort: 53}
+
+	err := embUDP.SetNetworkLayerForChecksum(&embIP6)
+	if err != nil {
+		t.Fatalf("set network layer for checksum: %v", err)
+	}
+
+	embBuf := gopacket.NewSerializeBuffer()
+	err = gopacket.SerializeLayers(embBuf, gopacket.SerializeOptions{ComputeChecksums: true, FixLengths: true},
+		&embIP6, &embUDP)
+	if err != nil {
+		t.Fatalf("serialize embedded packet: %v", err)
+	}
+
+	err = icmp6.SetNetworkLayerForChecksum(&ip6)
+	if err != nil {
+		t.Fatalf("set network layer for checksum: %v", err)
+	}
+
+	buf := gopacket.NewSerializeBuffer()
+	opts := gopacket.SerializeOptions{ComputeChecksums: true, FixLengths: true}
+
+	// The 4-byte message body field (unused for Destination Unreachable) precedes the
+	// embedded packet.
+	payload := append(make([]byte, 4), embBuf.Bytes()...)
+
+	err = gopacket.SerializeLayers(buf, opts, &eth, &ip6, &icmp6, gopacket.Payload(payload))
+	if err != nil {
+		t.Fatalf("serialize layers: %v", err)
+	}
+
+	packet := gopacket.NewPacket(buf.Bytes(), layers.LayerTypeEthernet, gopacket.Default)
+
+	indicator, err := ParsePacket(packet)
+	if err != nil {
+		t.Fatalf("parse packet: %v", err)
+	}
+
+	icmpv6Indicator := indicator.ICMPv6Indicator()
+	if icmpv6Indicator == nil {
+		t.Fatalf("expected an ICMPv6 indicator")
+	}
+	if icmpv6Indicator.IsQuery() {
+		t.Fatalf("destination unreachable must not be reported as a query")
+	}
+
+	embIndicator := icmpv6Indicator.EmbIndicator()
+	if embIndicator == nil {
+		t.Fatalf("expected an embedded packet indicator")
+	}
+	if embIndicator.SrcIP().String() != "2001:db8::2" {
+		t.Fatalf("EmbSrc IP = %s, want 2001:db8::2", embIndicator.SrcIP())
+	}
+	if embIndicator.DstIP().String() != "2001:db8::1" {
+		t.Fatalf("EmbDst IP = %s, want 2001:db8::1", embIndicator.DstIP())
+	}
+	if icmpv6Indicator.EmbTransportLayer().LayerType() != layers.LayerTypeUDP {
+		t.Fatalf("EmbTransportLayer type = %s, want UDP", icmpv6Indicator.EmbTransportLayer().LayerType())
+	}
+}
+
+func TestParsePacket_ICMPv6NeighborSolicitation(t *testing.T) {
+	eth := layers.Ethernet{
+		SrcMAC:       net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x55},
+		DstMAC:       net.HardwareAddr{0x00, 0x66, 0x77, 0x88, 0x99, 0xaa},
+		EthernetType: layers.EthernetTypeIPv6,
+	}
+	ip6 := layers.IPv6{
+		Version:    6,
+		HopLimit:   255,
+		SrcIP:      net.ParseIP("2001:db8::1"),
+		DstIP:      net.ParseIP("2001:db8::2"),
+		NextHeader: layers.IPProtocolICMPv6,
+	}
+	icmp6 := layers.ICMPv6{
+		TypeCode: layers.CreateICMPv6TypeCode(layers.ICMPv6TypeNeighborSolicitation, 0),
+	}
+	ns := layers.ICMPv6NeighborSolicitation{
+		TargetAddress: net.ParseIP("2001:db8::2"),
+	}
+
+	err := icmp6.SetNetworkLayerForChecksum(&ip6)
+	if err != nil {
+		t.Fatalf("set network layer for checksum: %v", err)
+	}
+
+	buf := gopacket.NewSerializeBuffer()
+	opts := gopacket.SerializeOptions{ComputeChecksums: true, FixLengths: true}
+
+	err = gopacket.SerializeLayers(buf, opts, &eth, &ip6, &icmp6, &ns)
+	if err != nil {
+		t.Fatalf("serialize layers: %v", err)
+	}
+
+	packet := gopacket.NewPacket(buf.Bytes(), layers.LayerTypeEthernet, gopacket.Default)
+
+	indicator, err := ParsePacket(packet)
+	if err != nil {
+		t.Fatalf("parse packet: %v", err)
+	}
+
+	icmpv6Indicator := indicator.ICMPv6Indicator()
+	if icmpv6Indicator == nil {
+		t.Fatalf("expected an ICMPv6 indicator")
+	}
+	if icmpv6Indicator.IsQuery() {
+		t.Fatalf("neighbor solicitation must not be reported as a query")
+	}
+	if icmpv6Indicator.IsError() {
+		t.Fatalf("neighbor solicitation must not be reported as an error")
+	}
+
+	// Src/Dst don't need the (nonexistent) embedded packet, so they must work on Neighbor
+	// Discovery messages.
+	if indicator.Src().(*net.IPAddr).IP.String() != "2001:db8::1" {
+		t.Fatalf("Src() = %s, want 2001:db8::1", indicator.Src())
+	}
+	if indicator.Dst().(*net.IPAddr).IP.String() != "2001:db8::2" {
+		t.Fatalf("Dst() = %s, want 2001:db8::2", indicator.Dst())
+	}
+
+	// NATSrc/NATDst/NATProtocol have no translatable address for a message with neither a
+	// query id nor an embedded packet, so they must panic rather than nil-dereference.
+	assertPanics(t, "NATSrc", func() { indicator.NATSrc() })
+	assertPanics(t, "NATDst", func() { indicator.NATDst() })
+	assertPanics(t, "NATProtocol", func() { indicator.NATProtocol() })
+}
+
+func assertPanics(t *testing.T, name string, f func()) {
+	t.Helper()
+
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("%s: expected a panic, got none", name)
+		}
+	}()
+
+	f()
+}
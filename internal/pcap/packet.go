@@ -36,12 +36,16 @@ type NATGuide struct {
 // PacketIndicator indicates a packet.
 type PacketIndicator struct {
 	packet            gopacket.Packet
+	data              []byte
 	linkLayer         gopacket.Layer
+	vlanIDs           []uint16
 	networkLayer      gopacket.Layer
 	ipv6FragmentLayer *layers.IPv6Fragment
 	transportLayer    gopacket.Layer
 	icmpv4Indicator   *ICMPv4Indicator
+	icmpv6Indicator   *ICMPv6Indicator
 	applicationLayer  gopacket.ApplicationLayer
+	appLayers         []gopacket.Layer
 }
 
 // LinkLayer returns the link layer.
@@ -78,6 +82,39 @@ func (indicator *PacketIndicator) DstHardwareAddr() net.HardwareAddr {
 	}
 }
 
+// VLAN returns the VLAN IDs of the packet's 802.1Q tags, outermost first. It is empty for
+// untagged frames and has two entries for double-tagged (QinQ) frames. ParsePacket records no
+// tags for ARP frames, so it is also empty for a tagged ARP frame.
+func (indicator *PacketIndicator) VLAN() []uint16 {
+	return indicator.vlanIDs
+}
+
+// BuildVLANLayers returns the 802.1Q tag layers needed to re-wrap a frame in the same VLAN tags
+// indicator was received with, so a NAT-rewritten packet can be re-emitted on the tagged segment
+// it arrived on instead of silently being sent untagged. innerType is the ethertype the
+// innermost tag should carry (typically EthernetTypeIPv4 or EthernetTypeIPv6). It returns nil
+// for an indicator whose frame was not VLAN-tagged.
+//
+// The caller is still responsible for setting the outer Ethernet layer's EthernetType to
+// EthernetTypeDot1Q or EthernetTypeQinQ to match the number of tags returned.
+func BuildVLANLayers(indicator *PacketIndicator, innerType layers.EthernetType) []gopacket.SerializableLayer {
+	if len(indicator.vlanIDs) == 0 {
+		return nil
+	}
+
+	tags := make([]gopacket.SerializableLayer, len(indicator.vlanIDs))
+	for i, id := range indicator.vlanIDs {
+		t := innerType
+		if i < len(indicator.vlanIDs)-1 {
+			t = layers.EthernetTypeDot1Q
+		}
+
+		tags[i] = &layers.Dot1Q{VLANIdentifier: id, Type: t}
+	}
+
+	return tags
+}
+
 // NetworkLayer returns the network layer.
 func (indicator *PacketIndicator) NetworkLayer() gopacket.Layer {
 	return indicator.networkLayer
@@ -228,9 +265,9 @@ func (indicator *PacketIndicator) TransportProtocol() gopacket.LayerType {
 		)
 
 		if indicator.IsFrag() {
-			p, err = parseIPProtocol(indicator.IPv6Layer().NextHeader)
-		} else {
 			p, err = parseIPProtocol(indicator.ipv6FragmentLayer.NextHeader)
+		} else {
+			p, err = parseIPProtocol(indicator.IPv6Layer().NextHeader)
 		}
 		if err != nil {
 			panic(err)
@@ -265,11 +302,25 @@ func (indicator *PacketIndicator) UDPLayer() *layers.UDP {
 	return nil
 }
 
+// SCTPLayer returns the SCTP layer.
+func (indicator *PacketIndicator) SCTPLayer() *layers.SCTP {
+	if indicator.TransportLayer().LayerType() == layers.LayerTypeSCTP {
+		return indicator.transportLayer.(*layers.SCTP)
+	}
+
+	return nil
+}
+
 // ICMPv4Indicator returns the ICMPv4 indicator.
 func (indicator *PacketIndicator) ICMPv4Indicator() *ICMPv4Indicator {
 	return indicator.icmpv4Indicator
 }
 
+// ICMPv6Indicator returns the ICMPv6 indicator.
+func (indicator *PacketIndicator) ICMPv6Indicator() *ICMPv6Indicator {
+	return indicator.icmpv6Indicator
+}
+
 // SrcPort returns the source port.
 func (indicator *PacketIndicator) SrcPort() uint16 {
 	switch t := indicator.TransportLayer().LayerType(); t {
@@ -277,6 +328,8 @@ func (indicator *PacketIndicator) SrcPort() uint16 {
 		return uint16(indicator.TCPLayer().SrcPort)
 	case layers.LayerTypeUDP:
 		return uint16(indicator.UDPLayer().SrcPort)
+	case layers.LayerTypeSCTP:
+		return uint16(indicator.SCTPLayer().SrcPort)
 	default:
 		panic(fmt.Errorf("transport layer type %s not support", t))
 	}
@@ -289,6 +342,8 @@ func (indicator *PacketIndicator) DstPort() uint16 {
 		return uint16(indicator.TCPLayer().DstPort)
 	case layers.LayerTypeUDP:
 		return uint16(indicator.UDPLayer().DstPort)
+	case layers.LayerTypeSCTP:
+		return uint16(indicator.SCTPLayer().DstPort)
 	default:
 		panic(fmt.Errorf("transport layer type %s not support", t))
 	}
@@ -307,6 +362,13 @@ func (indicator *PacketIndicator) NATSrc() net.Addr {
 			IP:   indicator.SrcIP(),
 			Port: int(indicator.SrcPort()),
 		}
+	case layers.LayerTypeSCTP:
+		// SCTP is port-addressed like UDP, and the NAT map only needs IP and port to key on;
+		// net.UDPAddr is reused rather than adding a dedicated SCTP address type.
+		return &net.UDPAddr{
+			IP:   indicator.SrcIP(),
+			Port: int(indicator.SrcPort()),
+		}
 	case layers.LayerTypeICMPv4:
 		if indicator.icmpv4Indicator.IsQuery() {
 			return &addr.ICMPQueryAddr{
@@ -316,6 +378,18 @@ func (indicator *PacketIndicator) NATSrc() net.Addr {
 		}
 
 		return indicator.icmpv4Indicator.EmbSrc()
+	case layers.LayerTypeICMPv6:
+		if indicator.icmpv6Indicator.IsQuery() {
+			return &addr.ICMPQueryAddr{
+				IP: indicator.SrcIP(),
+				Id: indicator.icmpv6Indicator.Id(),
+			}
+		}
+		if indicator.icmpv6Indicator.IsError() {
+			return indicator.icmpv6Indicator.EmbSrc()
+		}
+
+		panic(fmt.Errorf("icmpv6 type %d not support in nat", indicator.icmpv6Indicator.ICMPv6Layer().TypeCode.Type()))
 	default:
 		panic(fmt.Errorf("transport layer type %s not support", t))
 	}
@@ -334,6 +408,11 @@ func (indicator *PacketIndicator) NATDst() net.Addr {
 			IP:   indicator.DstIP(),
 			Port: int(indicator.DstPort()),
 		}
+	case layers.LayerTypeSCTP:
+		return &net.UDPAddr{
+			IP:   indicator.DstIP(),
+			Port: int(indicator.DstPort()),
+		}
 	case layers.LayerTypeICMPv4:
 		if indicator.icmpv4Indicator.IsQuery() {
 			return &addr.ICMPQueryAddr{
@@ -343,6 +422,18 @@ func (indicator *PacketIndicator) NATDst() net.Addr {
 		}
 
 		return indicator.icmpv4Indicator.EmbDst()
+	case layers.LayerTypeICMPv6:
+		if indicator.icmpv6Indicator.IsQuery() {
+			return &addr.ICMPQueryAddr{
+				IP: indicator.DstIP(),
+				Id: indicator.icmpv6Indicator.Id(),
+			}
+		}
+		if indicator.icmpv6Indicator.IsError() {
+			return indicator.icmpv6Indicator.EmbDst()
+		}
+
+		panic(fmt.Errorf("icmpv6 type %d not support in nat", indicator.icmpv6Indicator.ICMPv6Layer().TypeCode.Type()))
 	default:
 		panic(fmt.Errorf("transport layer type %s not support", t))
 	}
@@ -351,7 +442,7 @@ func (indicator *PacketIndicator) NATDst() net.Addr {
 // NATProtocol returns the protocol used in NAT.
 func (indicator *PacketIndicator) NATProtocol() gopacket.LayerType {
 	switch t := indicator.TransportLayer().LayerType(); t {
-	case layers.LayerTypeTCP, layers.LayerTypeUDP:
+	case layers.LayerTypeTCP, layers.LayerTypeUDP, layers.LayerTypeSCTP:
 		return t
 	case layers.LayerTypeICMPv4:
 		if indicator.icmpv4Indicator.IsQuery() {
@@ -359,6 +450,15 @@ func (indicator *PacketIndicator) NATProtocol() gopacket.LayerType {
 		}
 
 		return indicator.icmpv4Indicator.EmbTransportLayer().LayerType()
+	case layers.LayerTypeICMPv6:
+		if indicator.icmpv6Indicator.IsQuery() {
+			return t
+		}
+		if indicator.icmpv6Indicator.IsError() {
+			return indicator.icmpv6Indicator.EmbTransportLayer().LayerType()
+		}
+
+		panic(fmt.Errorf("icmpv6 type %d not support in nat", indicator.icmpv6Indicator.ICMPv6Layer().TypeCode.Type()))
 	default:
 		panic(fmt.Errorf("transport layer type %s not support", t))
 	}
@@ -383,6 +483,11 @@ func (indicator *PacketIndicator) Src() net.Addr {
 			IP:   indicator.SrcIP(),
 			Port: int(indicator.SrcPort()),
 		}
+	case layers.LayerTypeSCTP:
+		return &net.UDPAddr{
+			IP:   indicator.SrcIP(),
+			Port: int(indicator.SrcPort()),
+		}
 	case layers.LayerTypeICMPv4:
 		if indicator.icmpv4Indicator.IsQuery() {
 			return &addr.ICMPQueryAddr{
@@ -391,6 +496,15 @@ func (indicator *PacketIndicator) Src() net.Addr {
 			}
 		}
 
+		return &net.IPAddr{IP: indicator.SrcIP()}
+	case layers.LayerTypeICMPv6:
+		if indicator.icmpv6Indicator.IsQuery() {
+			return &addr.ICMPQueryAddr{
+				IP: indicator.SrcIP(),
+				Id: indicator.icmpv6Indicator.Id(),
+			}
+		}
+
 		return &net.IPAddr{IP: indicator.SrcIP()}
 	default:
 		panic(fmt.Errorf("transport layer type %s not support", t))
@@ -416,6 +530,11 @@ func (indicator *PacketIndicator) Dst() net.Addr {
 			IP:   indicator.DstIP(),
 			Port: int(indicator.DstPort()),
 		}
+	case layers.LayerTypeSCTP:
+		return &net.UDPAddr{
+			IP:   indicator.DstIP(),
+			Port: int(indicator.DstPort()),
+		}
 	case layers.LayerTypeICMPv4:
 		if indicator.icmpv4Indicator.IsQuery() {
 			return &addr.ICMPQueryAddr{
@@ -424,6 +543,15 @@ func (indicator *PacketIndicator) Dst() net.Addr {
 			}
 		}
 
+		return &net.IPAddr{IP: indicator.DstIP()}
+	case layers.LayerTypeICMPv6:
+		if indicator.icmpv6Indicator.IsQuery() {
+			return &addr.ICMPQueryAddr{
+				IP: indicator.DstIP(),
+				Id: indicator.icmpv6Indicator.Id(),
+			}
+		}
+
 		return &net.IPAddr{IP: indicator.DstIP()}
 	default:
 		panic(fmt.Errorf("transport layer type %s not support", t))
@@ -455,9 +583,34 @@ func (indicator *PacketIndicator) Payload() []byte {
 	return payload
 }
 
+// AppLayers returns the layers decoded from the payload by the application layer decoder chain
+// registered with RegisterAppDecoder, in the order they were decoded. It is empty if no decoder
+// is registered for the packet's transport protocol and port, or the payload failed to decode.
+// Only ParsePacket runs the chain, so it is also empty for an indicator built by FastParser's
+// fast path.
+func (indicator *PacketIndicator) AppLayers() []gopacket.Layer {
+	return indicator.appLayers
+}
+
+// DNS returns the decoded DNS layer, or nil if the application layer decoder chain did not
+// produce one.
+func (indicator *PacketIndicator) DNS() *layers.DNS {
+	for _, l := range indicator.appLayers {
+		if dns, ok := l.(*layers.DNS); ok {
+			return dns
+		}
+	}
+
+	return nil
+}
+
 // Size returns the size of the packet.
 func (indicator *PacketIndicator) Size() int {
-	return len(indicator.packet.Data())
+	if indicator.packet != nil {
+		return len(indicator.packet.Data())
+	}
+
+	return len(indicator.data)
 }
 
 // ParsePacket parses a packet and returns a packet indicator.
@@ -468,6 +621,7 @@ func ParsePacket(packet gopacket.Packet) (*PacketIndicator, error) {
 		ipv6FragmentLayer *layers.IPv6Fragment
 		transportLayer    gopacket.Layer
 		icmpv4Indicator   *ICMPv4Indicator
+		icmpv6Indicator   *ICMPv6Indicator
 		applicationLayer  gopacket.ApplicationLayer
 	)
 
@@ -497,15 +651,20 @@ func ParsePacket(packet gopacket.Packet) (*PacketIndicator, error) {
 		// Guess ICMPv4
 		transportLayer = packet.Layer(layers.LayerTypeICMPv4)
 		if transportLayer == nil {
-			// Guess fragment
-			if packet.Layer(gopacket.LayerTypeFragment) == nil {
-				return nil, errors.New("missing transport layer")
+			// Guess ICMPv6
+			transportLayer = packet.Layer(layers.LayerTypeICMPv6)
+			if transportLayer == nil {
+				// Guess fragment
+				if packet.Layer(gopacket.LayerTypeFragment) == nil {
+					return nil, errors.New("missing transport layer")
+				}
 			}
 		}
 	}
 	applicationLayer = packet.ApplicationLayer()
 
 	// Parse link layer
+	var vlanIDs []uint16
 	if linkLayer != nil {
 		switch t := linkLayer.LayerType(); t {
 		case layers.LayerTypeLoopback:
@@ -513,7 +672,22 @@ func ParsePacket(packet gopacket.Packet) (*PacketIndicator, error) {
 		case layers.LayerTypeEthernet:
 			ethernetLayer := linkLayer.(*layers.Ethernet)
 
-			_, err := parseEthernetType(ethernetLayer.EthernetType)
+			// Walk through up to two 802.1Q tags (QinQ), resolving the inner ethertype
+			innerType := ethernetLayer.EthernetType
+			for _, l := range packet.Layers() {
+				dot1qLayer, ok := l.(*layers.Dot1Q)
+				if !ok {
+					continue
+				}
+				if len(vlanIDs) >= 2 {
+					return nil, errors.New("more than two 802.1q tags not support")
+				}
+
+				vlanIDs = append(vlanIDs, dot1qLayer.VLANIdentifier)
+				innerType = dot1qLayer.Type
+			}
+
+			_, err := parseEthernetType(innerType)
 			if err != nil {
 				return nil, err
 			}
@@ -557,29 +731,55 @@ func ParsePacket(packet gopacket.Packet) (*PacketIndicator, error) {
 	}
 
 	// Parse transport layer
+	var srcPort, dstPort uint16
 	if transportLayer != nil {
 		switch t := transportLayer.LayerType(); t {
-		case layers.LayerTypeTCP, layers.LayerTypeUDP:
-			break
+		case layers.LayerTypeTCP:
+			tcpLayer := transportLayer.(*layers.TCP)
+			srcPort, dstPort = uint16(tcpLayer.SrcPort), uint16(tcpLayer.DstPort)
+		case layers.LayerTypeUDP:
+			udpLayer := transportLayer.(*layers.UDP)
+			srcPort, dstPort = uint16(udpLayer.SrcPort), uint16(udpLayer.DstPort)
+		case layers.LayerTypeSCTP:
+			sctpLayer := transportLayer.(*layers.SCTP)
+			srcPort, dstPort = uint16(sctpLayer.SrcPort), uint16(sctpLayer.DstPort)
 		case layers.LayerTypeICMPv4:
 			var err error
 			icmpv4Indicator, err = ParseICMPv4Layer(transportLayer.(*layers.ICMPv4))
 			if err != nil {
 				return nil, fmt.Errorf("parse icmpv4 layer: %w", err)
 			}
+		case layers.LayerTypeICMPv6:
+			var err error
+			icmpv6Indicator, err = ParseICMPv6Layer(transportLayer.(*layers.ICMPv6))
+			if err != nil {
+				return nil, fmt.Errorf("parse icmpv6 layer: %w", err)
+			}
 		default:
 			return nil, fmt.Errorf("transport layer type %s not support", t)
 		}
 	}
 
+	// Run the registered application layer decoder chain, if any, over the payload
+	var appLayers []gopacket.Layer
+	if transportLayer != nil && applicationLayer != nil {
+		switch transportLayer.LayerType() {
+		case layers.LayerTypeTCP, layers.LayerTypeUDP, layers.LayerTypeSCTP:
+			appLayers = runAppDecoder(packet, transportLayer.LayerType(), srcPort, dstPort, applicationLayer.LayerContents())
+		}
+	}
+
 	return &PacketIndicator{
 		packet:            packet,
 		linkLayer:         linkLayer,
+		vlanIDs:           vlanIDs,
 		networkLayer:      networkLayer,
 		ipv6FragmentLayer: ipv6FragmentLayer,
 		transportLayer:    transportLayer,
 		icmpv4Indicator:   icmpv4Indicator,
+		icmpv6Indicator:   icmpv6Indicator,
 		applicationLayer:  applicationLayer,
+		appLayers:         appLayers,
 	}, nil
 }
 
@@ -607,6 +807,8 @@ func ParseEmbPacket(contents []byte) (*PacketIndicator, error) {
 		if networkLayer.LayerType() != layers.LayerTypeIPv6 {
 			return nil, errors.New("network layer type not support")
 		}
+
+		packet = embPacket
 	default:
 		return nil, errors.New("network layer type not support")
 	}
@@ -691,6 +893,8 @@ func parseIPProtocol(protocol layers.IPProtocol) (gopacket.LayerType, error) {
 		return layers.LayerTypeICMPv4, nil
 	case layers.IPProtocolICMPv6:
 		return layers.LayerTypeICMPv6, nil
+	case layers.IPProtocolSCTP:
+		return layers.LayerTypeSCTP, nil
 	default:
 		return gopacket.LayerTypeZero, fmt.Errorf("ip protocol %s not support", protocol)
 	}
@@ -0,0 +1,113 @@
+package pcap
+
+import (
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// PacketDataReader is the narrow read surface a live pcap handle or RawConn exposes
+// (ReadPacketData), the same contract vnet.VConn implements so tests can drive it without a
+// live capture.
+type PacketDataReader interface {
+	ReadPacketData() ([]byte, error)
+}
+
+// PacketReader reads and parses packets off a PacketDataReader, borrowing a FastParser from a
+// FastParserPool per packet instead of allocating a DecodingLayerParser from scratch, and
+// optionally reassembling IPv4/IPv6 fragments into a single PacketIndicator before handing it to
+// the caller.
+//
+// A PacketReader is not safe for concurrent use.
+type PacketReader struct {
+	conn        PacketDataReader
+	parsers     *FastParserPool
+	reassembler *Reassembler
+
+	reassemble bool
+}
+
+// NewPacketReader returns a PacketReader that reads from conn, decoding packets whose link
+// layer starts with firstLayerType. Fragment reassembly is off by default; enable it with
+// SetReassembly.
+func NewPacketReader(conn PacketDataReader, firstLayerType gopacket.LayerType) *PacketReader {
+	return &PacketReader{
+		conn:        conn,
+		parsers:     NewFastParserPool(firstLayerType),
+		reassembler: NewReassembler(),
+	}
+}
+
+// SetReassembly enables or disables IPv4/IPv6 fragment reassembly for subsequent calls to
+// ReadPacket. It is off by default, matching FastParser's low-allocation fast path; enabling it
+// lets ReadPacket transparently return whole datagrams to callers that need to inspect payloads
+// spanning fragments (for example the application layer decoder chain), at the cost of buffering
+// fragments until their flow completes or times out.
+func (r *PacketReader) SetReassembly(enabled bool) {
+	r.reassemble = enabled
+}
+
+// ReadPacket reads packets from the underlying connection until it has a complete
+// PacketIndicator to return, buffering fragments in the Reassembler when reassembly is enabled.
+//
+// release must be called once the caller is done with the returned indicator (and anything
+// obtained through it, such as its layers) so a FastParser borrowed from the pool can be
+// returned for reuse. release is a no-op for an indicator that does not point into pooled
+// memory, such as one a completed reassembly rebuilt independently.
+func (r *PacketReader) ReadPacket() (*PacketIndicator, func(), error) {
+	for {
+		data, err := r.conn.ReadPacketData()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		parser := r.parsers.Get()
+
+		indicator, err := parser.Parse(data)
+		if err != nil {
+			r.parsers.Put(parser)
+
+			return nil, nil, err
+		}
+
+		if !r.reassemble || !isIPNetworkLayer(indicator) || !indicator.IsFrag() {
+			return indicator, func() { r.parsers.Put(parser) }, nil
+		}
+
+		// The fragment may need to outlive this read (and this pooled parser) until the rest
+		// of its flow arrives, so re-parse it through the slow, independently-allocated path
+		// before returning the parser to the pool.
+		r.parsers.Put(parser)
+
+		packet, err := ParseRawPacket(data)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		slowIndicator, err := ParsePacket(packet)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		result, ok, err := r.reassembler.Add(slowIndicator)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !ok {
+			continue
+		}
+
+		return result, func() {}, nil
+	}
+}
+
+// isIPNetworkLayer reports whether indicator's network layer is IPv4 or IPv6, the only layer
+// types IsFrag understands; it panics on anything else. FastParser also returns indicators for
+// ARP and other non-IP network layers, which ReadPacket must not pass to IsFrag.
+func isIPNetworkLayer(indicator *PacketIndicator) bool {
+	switch indicator.NetworkLayer().LayerType() {
+	case layers.LayerTypeIPv4, layers.LayerTypeIPv6:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,160 @@
+package pcap
+
+import (
+	"net"
+	"testing"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+
+	"ikago/internal/pcap/vnet"
+)
+
+func TestPacketReader_ReadsThroughFastParserPool(t *testing.T) {
+	v := vnet.NewVNet()
+
+	a := &vnet.Node{MAC: net.HardwareAddr{0, 1, 2, 3, 4, 5}, IP: net.IPv4(192, 168, 1, 1)}
+	b := &vnet.Node{MAC: net.HardwareAddr{0, 1, 2, 3, 4, 6}, IP: net.IPv4(192, 168, 1, 2)}
+
+	connA := v.AddNode(a)
+	connB := v.AddNode(b)
+
+	eth := layers.Ethernet{SrcMAC: a.MAC, DstMAC: b.MAC, EthernetType: layers.EthernetTypeIPv4}
+	ip4 := layers.IPv4{Version: 4, TTL: 64, SrcIP: a.IP, DstIP: b.IP, Protocol: layers.IPProtocolUDP}
+	udp := layers.UDP{SrcPort: 1000, DstPort: 2000}
+
+	err := udp.SetNetworkLayerForChecksum(&ip4)
+	if err != nil {
+		t.Fatalf("set network layer for checksum: %v", err)
+	}
+
+	frame, err := vnet.BuildEthernetIPv4(&eth, &ip4, &udp, gopacket.Payload("hello"))
+	if err != nil {
+		t.Fatalf("build frame: %v", err)
+	}
+
+	if err := connA.WritePacketData(frame); err != nil {
+		t.Fatalf("write packet data: %v", err)
+	}
+
+	reader := NewPacketReader(connB, layers.LayerTypeEthernet)
+
+	indicator, release, err := reader.ReadPacket()
+	if err != nil {
+		t.Fatalf("read packet: %v", err)
+	}
+	defer release()
+
+	if !indicator.SrcIP().Equal(a.IP) || !indicator.DstIP().Equal(b.IP) {
+		t.Fatalf("unexpected src/dst: %s -> %s", indicator.SrcIP(), indicator.DstIP())
+	}
+	if string(indicator.Payload()) != "hello" {
+		t.Fatalf("payload = %q, want %q", indicator.Payload(), "hello")
+	}
+}
+
+func TestPacketReader_ReassemblesFragmentsWhenEnabled(t *testing.T) {
+	v := vnet.NewVNet()
+
+	a := &vnet.Node{MAC: net.HardwareAddr{0, 1, 2, 3, 4, 7}, IP: net.IPv4(192, 168, 2, 1)}
+	b := &vnet.Node{MAC: net.HardwareAddr{0, 1, 2, 3, 4, 8}, IP: net.IPv4(192, 168, 2, 2)}
+
+	connA := v.AddNode(a)
+	connB := v.AddNode(b)
+
+	first := make([]byte, 8)
+	for i := range first {
+		first[i] = byte(i)
+	}
+	second := []byte{0xde, 0xad}
+
+	for i, piece := range [][]byte{first, second} {
+		flags := layers.IPv4MoreFragments
+		offset := uint16(0)
+		if i == 1 {
+			flags = 0
+			offset = 1
+		}
+
+		eth := layers.Ethernet{SrcMAC: a.MAC, DstMAC: b.MAC, EthernetType: layers.EthernetTypeIPv4}
+		ip4 := layers.IPv4{
+			Version: 4, TTL: 64, Id: 7,
+			Flags: flags, FragOffset: offset,
+			SrcIP: a.IP, DstIP: b.IP, Protocol: layers.IPProtocolUDP,
+		}
+
+		frame, err := vnet.BuildEthernetIPv4(&eth, &ip4, gopacket.Payload(piece))
+		if err != nil {
+			t.Fatalf("build fragment %d: %v", i, err)
+		}
+
+		if err := connA.WritePacketData(frame); err != nil {
+			t.Fatalf("write fragment %d: %v", i, err)
+		}
+	}
+
+	reader := NewPacketReader(connB, layers.LayerTypeEthernet)
+	reader.SetReassembly(true)
+
+	indicator, release, err := reader.ReadPacket()
+	if err != nil {
+		t.Fatalf("read packet: %v", err)
+	}
+	defer release()
+
+	want := append(append([]byte{}, first...), second...)
+	if got := indicator.NetworkPayload(); string(got) != string(want) {
+		t.Fatalf("reassembled payload = %x, want %x", got, want)
+	}
+}
+
+func TestPacketReader_ReassemblyEnabledDoesNotPanicOnARP(t *testing.T) {
+	v := vnet.NewVNet()
+
+	a := &vnet.Node{MAC: net.HardwareAddr{0, 1, 2, 3, 4, 9}, IP: net.IPv4(192, 168, 3, 1)}
+	b := &vnet.Node{MAC: net.HardwareAddr{0, 1, 2, 3, 4, 10}, IP: net.IPv4(192, 168, 3, 2)}
+
+	connA := v.AddNode(a)
+	connB := v.AddNode(b)
+
+	eth := layers.Ethernet{SrcMAC: a.MAC, DstMAC: b.MAC, EthernetType: layers.EthernetTypeARP}
+	arp := layers.ARP{
+		AddrType:          layers.LinkTypeEthernet,
+		Protocol:          layers.EthernetTypeIPv4,
+		HwAddressSize:     6,
+		ProtAddressSize:   4,
+		Operation:         layers.ARPRequest,
+		SourceHwAddress:   a.MAC,
+		SourceProtAddress: a.IP.To4(),
+		DstHwAddress:      b.MAC,
+		DstProtAddress:    b.IP.To4(),
+	}
+
+	buf := gopacket.NewSerializeBuffer()
+	opts := gopacket.SerializeOptions{ComputeChecksums: true, FixLengths: true}
+
+	if err := gopacket.SerializeLayers(buf, opts, &eth, &arp); err != nil {
+		t.Fatalf("serialize layers: %v", err)
+	}
+
+	frame := make([]byte, len(buf.Bytes()))
+	copy(frame, buf.Bytes())
+
+	if err := connA.WritePacketData(frame); err != nil {
+		t.Fatalf("write packet data: %v", err)
+	}
+
+	reader := NewPacketReader(connB, layers.LayerTypeEthernet)
+	reader.SetReassembly(true)
+
+	// Must not panic in IsFrag: ARP has no concept of fragmentation.
+	indicator, release, err := reader.ReadPacket()
+	if err != nil {
+		t.Fatalf("read packet: %v", err)
+	}
+	defer release()
+
+	if indicator.NetworkLayer().LayerType() != layers.LayerTypeARP {
+		t.Fatalf("network layer type = %s, want ARP", indicator.NetworkLayer().LayerType())
+	}
+}
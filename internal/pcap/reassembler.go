@@ -0,0 +1,222 @@
+package pcap
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// DefaultReassemblyTimeout is how long an incomplete fragmented flow is kept before being
+// dropped when a Reassembler's Timeout is left at zero, per the reassembly timeout guidance in
+// RFC 791 and RFC 8200.
+const DefaultReassemblyTimeout = 30 * time.Second
+
+// fragKey identifies a fragmented IPv4 or IPv6 flow.
+type fragKey struct {
+	srcIP     string
+	dstIP     string
+	networkId uint
+	protocol  gopacket.LayerType
+}
+
+type fragPiece struct {
+	offset  int
+	payload []byte
+}
+
+type fragFlow struct {
+	head     *PacketIndicator
+	pieces   []fragPiece
+	haveTail bool
+	totalLen int
+	deadline time.Time
+}
+
+// Reassembler reassembles fragmented IPv4 and IPv6 datagrams that ParsePacket and FastParser
+// otherwise only ever see piece by piece, which breaks NAT lookup and application layer
+// inspection whenever the path MTU forces fragmentation. Fragments are buffered keyed by
+// (SrcIP, DstIP, NetworkId, Protocol), ordered by FragOffset, and emitted as a single
+// PacketIndicator once the offset-0 head and a MoreFragments=false tail are both present and
+// no gaps remain between them. Incomplete flows are dropped after Timeout.
+//
+// A Reassembler is safe for concurrent use.
+type Reassembler struct {
+	// Timeout is how long an incomplete flow is kept before being dropped. Zero means
+	// DefaultReassemblyTimeout.
+	Timeout time.Duration
+
+	mu    sync.Mutex
+	flows map[fragKey]*fragFlow
+}
+
+// NewReassembler returns an empty Reassembler using DefaultReassemblyTimeout.
+func NewReassembler() *Reassembler {
+	return &Reassembler{flows: make(map[fragKey]*fragFlow)}
+}
+
+func (r *Reassembler) timeout() time.Duration {
+	if r.Timeout <= 0 {
+		return DefaultReassemblyTimeout
+	}
+
+	return r.Timeout
+}
+
+// Add feeds indicator into the reassembler. If indicator is not a fragment, it is returned
+// unchanged and ok is true. If indicator completes a buffered flow, the fully reassembled
+// PacketIndicator is returned and ok is true. Otherwise the fragment is buffered until the flow
+// completes or times out, and Add returns (nil, false, nil).
+//
+// Add rejects overlapping fragments, which can indicate a Teardrop-style attack, by dropping the
+// flow and returning an error rather than reassembling corrupt data.
+func (r *Reassembler) Add(indicator *PacketIndicator) (result *PacketIndicator, ok bool, err error) {
+	if !indicator.IsFrag() {
+		return indicator, true, nil
+	}
+
+	offset := int(indicator.FragOffset()) * 8
+	payload := indicator.NetworkPayload()
+	last := !indicator.MoreFragments()
+
+	if !last && len(payload)%8 != 0 {
+		return nil, false, fmt.Errorf("fragment from %s to %s: payload length %d is not a multiple of 8 bytes",
+			indicator.SrcIP(), indicator.DstIP(), len(payload))
+	}
+
+	key := fragKey{
+		srcIP:     indicator.SrcIP().String(),
+		dstIP:     indicator.DstIP().String(),
+		networkId: indicator.NetworkId(),
+		protocol:  indicator.TransportProtocol(),
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.reapLocked()
+
+	flow, existing := r.flows[key]
+	if !existing {
+		flow = &fragFlow{deadline: time.Now().Add(r.timeout())}
+		r.flows[key] = flow
+	}
+
+	for _, p := range flow.pieces {
+		if p.offset == offset && len(p.payload) == len(payload) {
+			// Identical retransmitted fragment: a no-op, not an overlap.
+			return nil, false, nil
+		}
+
+		if overlaps(p.offset, len(p.payload), offset, len(payload)) {
+			delete(r.flows, key)
+
+			return nil, false, fmt.Errorf("overlapping fragment from %s to %s (network id %d)",
+				indicator.SrcIP(), indicator.DstIP(), indicator.NetworkId())
+		}
+	}
+
+	flow.pieces = append(flow.pieces, fragPiece{offset: offset, payload: payload})
+	sort.Slice(flow.pieces, func(i, j int) bool { return flow.pieces[i].offset < flow.pieces[j].offset })
+
+	if offset == 0 {
+		flow.head = indicator
+	}
+	if last {
+		flow.haveTail = true
+		flow.totalLen = offset + len(payload)
+	}
+
+	if flow.head == nil || !flow.haveTail {
+		return nil, false, nil
+	}
+
+	reassembled, complete := joinFragments(flow)
+	if !complete {
+		return nil, false, nil
+	}
+
+	delete(r.flows, key)
+
+	result, err = buildReassembledIndicator(flow.head, reassembled)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return result, true, nil
+}
+
+// reapLocked drops flows whose Timeout has elapsed. Callers must hold r.mu.
+func (r *Reassembler) reapLocked() {
+	now := time.Now()
+
+	for k, f := range r.flows {
+		if now.After(f.deadline) {
+			delete(r.flows, k)
+		}
+	}
+}
+
+func overlaps(aOffset, aLen, bOffset, bLen int) bool {
+	return aOffset < bOffset+bLen && bOffset < aOffset+aLen
+}
+
+// joinFragments concatenates a flow's pieces in offset order and reports whether they form a
+// contiguous run from 0 to totalLen with no gaps.
+func joinFragments(flow *fragFlow) ([]byte, bool) {
+	buf := make([]byte, 0, flow.totalLen)
+
+	for _, p := range flow.pieces {
+		if p.offset != len(buf) {
+			return nil, false
+		}
+
+		buf = append(buf, p.payload...)
+	}
+
+	return buf, len(buf) == flow.totalLen
+}
+
+// buildReassembledIndicator rebuilds a complete, non-fragmented datagram from head, the
+// offset-0 fragment's indicator, and payload, the concatenated fragment payloads, then parses
+// it like any other packet.
+func buildReassembledIndicator(head *PacketIndicator, payload []byte) (*PacketIndicator, error) {
+	opts := gopacket.SerializeOptions{ComputeChecksums: true, FixLengths: true}
+
+	switch t := head.NetworkLayer().LayerType(); t {
+	case layers.LayerTypeIPv4:
+		ip4 := *head.IPv4Layer()
+		ip4.Flags &^= layers.IPv4MoreFragments
+		ip4.FragOffset = 0
+
+		buf := gopacket.NewSerializeBuffer()
+
+		err := gopacket.SerializeLayers(buf, opts, &ip4, gopacket.Payload(payload))
+		if err != nil {
+			return nil, fmt.Errorf("serialize reassembled ipv4 datagram: %w", err)
+		}
+
+		packet := gopacket.NewPacket(buf.Bytes(), layers.LayerTypeIPv4, gopacket.Default)
+
+		return ParsePacket(packet)
+	case layers.LayerTypeIPv6:
+		ip6 := *head.IPv6Layer()
+		ip6.NextHeader = head.ipv6FragmentLayer.NextHeader
+
+		buf := gopacket.NewSerializeBuffer()
+
+		err := gopacket.SerializeLayers(buf, opts, &ip6, gopacket.Payload(payload))
+		if err != nil {
+			return nil, fmt.Errorf("serialize reassembled ipv6 datagram: %w", err)
+		}
+
+		packet := gopacket.NewPacket(buf.Bytes(), layers.LayerTypeIPv6, gopacket.Default)
+
+		return ParsePacket(packet)
+	default:
+		return nil, fmt.Errorf("network layer type %s not support", t)
+	}
+}
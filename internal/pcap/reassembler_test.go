@@ -0,0 +1,192 @@
+package pcap
+
+import (
+	"bytes"
+	"net"
+	"testing"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+func serializeIPv4Fragment(t *testing.T, srcIP, dstIP net.IP, id uint16, offset uint16, moreFragments bool, payload []byte) *PacketIndicator {
+	t.Helper()
+
+	flags := layers.IPv4DontFragment
+	if moreFragments {
+		flags = layers.IPv4MoreFragments
+	}
+
+	ip4 := layers.IPv4{
+		Version:    4,
+		TTL:        64,
+		Id:         id,
+		Flags:      flags,
+		FragOffset: offset,
+		SrcIP:      srcIP,
+		DstIP:      dstIP,
+		Protocol:   layers.IPProtocolUDP,
+	}
+	buf := gopacket.NewSerializeBuffer()
+	opts := gopacket.SerializeOptions{ComputeChecksums: true, FixLengths: true}
+
+	err := gopacket.SerializeLayers(buf, opts, &ip4, gopacket.Payload(payload))
+	if err != nil {
+		t.Fatalf("serialize layers: %v", err)
+	}
+
+	packet := gopacket.NewPacket(buf.Bytes(), layers.LayerTypeIPv4, gopacket.Default)
+
+	indicator, err := ParsePacket(packet)
+	if err != nil {
+		t.Fatalf("parse packet: %v", err)
+	}
+
+	return indicator
+}
+
+func TestReassembler_IPv4(t *testing.T) {
+	srcIP := net.IPv4(192, 168, 1, 1)
+	dstIP := net.IPv4(192, 168, 1, 2)
+
+	first := make([]byte, 8)
+	for i := range first {
+		first[i] = byte(i)
+	}
+	second := []byte{0xaa, 0xbb, 0xcc}
+
+	r := NewReassembler()
+
+	frag1 := serializeIPv4Fragment(t, srcIP, dstIP, 1, 0, true, first)
+	result, ok, err := r.Add(frag1)
+	if err != nil {
+		t.Fatalf("add first fragment: %v", err)
+	}
+	if ok {
+		t.Fatalf("reassembly should not be complete after only the first fragment")
+	}
+
+	frag2 := serializeIPv4Fragment(t, srcIP, dstIP, 1, 1, false, second)
+	result, ok, err = r.Add(frag2)
+	if err != nil {
+		t.Fatalf("add second fragment: %v", err)
+	}
+	if !ok || result == nil {
+		t.Fatalf("expected reassembly to complete after the final fragment")
+	}
+
+	got := result.NetworkPayload()
+	want := append(append([]byte{}, first...), second...)
+	if !bytes.Equal(got, want) {
+		t.Fatalf("reassembled payload = %x, want %x", got, want)
+	}
+}
+
+func TestReassembler_IPv4_RejectsOverlap(t *testing.T) {
+	srcIP := net.IPv4(10, 0, 0, 1)
+	dstIP := net.IPv4(10, 0, 0, 2)
+
+	r := NewReassembler()
+
+	frag1 := serializeIPv4Fragment(t, srcIP, dstIP, 2, 0, true, make([]byte, 16))
+	if _, _, err := r.Add(frag1); err != nil {
+		t.Fatalf("add first fragment: %v", err)
+	}
+
+	// Covers bytes [8,24), overlapping bytes [8,16) already claimed by frag1.
+	frag2 := serializeIPv4Fragment(t, srcIP, dstIP, 2, 1, false, make([]byte, 16))
+
+	_, ok, err := r.Add(frag2)
+	if err == nil {
+		t.Fatalf("expected an error for an overlapping fragment")
+	}
+	if ok {
+		t.Fatalf("an overlapping fragment must not be reported complete")
+	}
+}
+
+func TestReassembler_IPv4_RejectsMisalignedNonFinalFragment(t *testing.T) {
+	srcIP := net.IPv4(10, 0, 0, 1)
+	dstIP := net.IPv4(10, 0, 0, 2)
+
+	r := NewReassembler()
+
+	// 5 bytes is not a multiple of 8, and MoreFragments is set, so this must be rejected.
+	frag := serializeIPv4Fragment(t, srcIP, dstIP, 3, 0, true, make([]byte, 5))
+
+	_, _, err := r.Add(frag)
+	if err == nil {
+		t.Fatalf("expected an error for a non-final fragment whose length is not a multiple of 8")
+	}
+}
+
+func serializeIPv6Fragment(t *testing.T, srcIP, dstIP net.IP, id uint32, offset uint16, moreFragments bool, payload []byte) *PacketIndicator {
+	t.Helper()
+
+	ip6 := layers.IPv6{
+		Version:    6,
+		HopLimit:   64,
+		SrcIP:      srcIP,
+		DstIP:      dstIP,
+		NextHeader: layers.IPProtocolIPv6Fragment,
+	}
+	frag := layers.IPv6Fragment{
+		NextHeader:     layers.IPProtocolUDP,
+		FragmentOffset: offset,
+		MoreFragments:  moreFragments,
+		Identification: id,
+	}
+
+	buf := gopacket.NewSerializeBuffer()
+	opts := gopacket.SerializeOptions{ComputeChecksums: false, FixLengths: true}
+
+	err := gopacket.SerializeLayers(buf, opts, &ip6, &frag, gopacket.Payload(payload))
+	if err != nil {
+		t.Fatalf("serialize layers: %v", err)
+	}
+
+	packet := gopacket.NewPacket(buf.Bytes(), layers.LayerTypeIPv6, gopacket.Default)
+
+	indicator, err := ParsePacket(packet)
+	if err != nil {
+		t.Fatalf("parse packet: %v", err)
+	}
+
+	return indicator
+}
+
+func TestReassembler_IPv6(t *testing.T) {
+	srcIP := net.ParseIP("2001:db8::1")
+	dstIP := net.ParseIP("2001:db8::2")
+
+	first := make([]byte, 8)
+	for i := range first {
+		first[i] = byte(0x10 + i)
+	}
+	second := []byte{0x01, 0x02}
+
+	r := NewReassembler()
+
+	frag1 := serializeIPv6Fragment(t, srcIP, dstIP, 42, 0, true, first)
+	if _, ok, err := r.Add(frag1); err != nil || ok {
+		t.Fatalf("add first fragment: ok=%v err=%v", ok, err)
+	}
+
+	frag2 := serializeIPv6Fragment(t, srcIP, dstIP, 42, 1, false, second)
+	result, ok, err := r.Add(frag2)
+	if err != nil {
+		t.Fatalf("add second fragment: %v", err)
+	}
+	if !ok || result == nil {
+		t.Fatalf("expected reassembly to complete after the final fragment")
+	}
+
+	got := result.NetworkPayload()
+	want := append(append([]byte{}, first...), second...)
+	if !bytes.Equal(got, want) {
+		t.Fatalf("reassembled payload = %x, want %x", got, want)
+	}
+	if result.TransportProtocol() != layers.LayerTypeUDP {
+		t.Fatalf("reassembled transport protocol = %s, want UDP", result.TransportProtocol())
+	}
+}
@@ -0,0 +1,94 @@
+package pcap
+
+import (
+	"net"
+	"testing"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// SCTP chunk type codes (RFC 4960 section 3.2), used here only to stamp a realistic chunk
+// header onto the payload; ikago does not need to understand individual chunk bodies.
+const (
+	sctpChunkTypeInit       = 1
+	sctpChunkTypeInitAck    = 2
+	sctpChunkTypeCookieEcho = 10
+)
+
+func buildSCTPPacket(t *testing.T, srcPort, dstPort uint16, chunkType byte) []byte {
+	t.Helper()
+
+	eth := layers.Ethernet{
+		SrcMAC:       net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x55},
+		DstMAC:       net.HardwareAddr{0x00, 0x66, 0x77, 0x88, 0x99, 0xaa},
+		EthernetType: layers.EthernetTypeIPv4,
+	}
+	ip4 := layers.IPv4{
+		Version:  4,
+		TTL:      64,
+		SrcIP:    net.IPv4(10, 0, 0, 1),
+		DstIP:    net.IPv4(10, 0, 0, 2),
+		Protocol: layers.IPProtocolSCTP,
+	}
+	sctp := layers.SCTP{
+		SrcPort: layers.SCTPPort(srcPort),
+		DstPort: layers.SCTPPort(dstPort),
+	}
+
+	// A minimal chunk header (type, flags, length) is enough to exercise the transport layer
+	// parsing path; the chunk body is irrelevant to ParsePacket.
+	chunk := []byte{chunkType, 0x00, 0x00, 0x04}
+
+	buf := gopacket.NewSerializeBuffer()
+	opts := gopacket.SerializeOptions{ComputeChecksums: true, FixLengths: true}
+
+	err := gopacket.SerializeLayers(buf, opts, &eth, &ip4, &sctp, gopacket.Payload(chunk))
+	if err != nil {
+		t.Fatalf("serialize layers: %v", err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestParsePacket_SCTP(t *testing.T) {
+	tests := []struct {
+		name      string
+		chunkType byte
+	}{
+		{"init", sctpChunkTypeInit},
+		{"init ack", sctpChunkTypeInitAck},
+		{"cookie echo", sctpChunkTypeCookieEcho},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := buildSCTPPacket(t, 3868, 38412, tt.chunkType)
+
+			packet := gopacket.NewPacket(data, layers.LayerTypeEthernet, gopacket.Default)
+
+			indicator, err := ParsePacket(packet)
+			if err != nil {
+				t.Fatalf("parse packet: %v", err)
+			}
+
+			if indicator.TransportLayer().LayerType() != layers.LayerTypeSCTP {
+				t.Fatalf("transport layer type = %s, want SCTP", indicator.TransportLayer().LayerType())
+			}
+			if indicator.SCTPLayer() == nil {
+				t.Fatalf("expected SCTPLayer() to return the decoded layer")
+			}
+			if indicator.SrcPort() != 3868 || indicator.DstPort() != 38412 {
+				t.Fatalf("ports = %d/%d, want 3868/38412", indicator.SrcPort(), indicator.DstPort())
+			}
+
+			natSrc, ok := indicator.NATSrc().(*net.UDPAddr)
+			if !ok || natSrc.Port != 3868 {
+				t.Fatalf("NATSrc() = %#v, want UDPAddr port 3868", indicator.NATSrc())
+			}
+			if indicator.NATProtocol() != layers.LayerTypeSCTP {
+				t.Fatalf("NATProtocol() = %s, want SCTP", indicator.NATProtocol())
+			}
+		})
+	}
+}
@@ -0,0 +1,195 @@
+package pcap
+
+import (
+	"net"
+	"testing"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+func TestParsePacket_Dot1Q(t *testing.T) {
+	eth := layers.Ethernet{
+		SrcMAC:       net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x55},
+		DstMAC:       net.HardwareAddr{0x00, 0x66, 0x77, 0x88, 0x99, 0xaa},
+		EthernetType: layers.EthernetTypeDot1Q,
+	}
+	dot1q := layers.Dot1Q{
+		VLANIdentifier: 42,
+		Type:           layers.EthernetTypeIPv4,
+	}
+	ip4 := layers.IPv4{
+		Version:  4,
+		TTL:      64,
+		SrcIP:    net.IPv4(192, 168, 1, 1),
+		DstIP:    net.IPv4(192, 168, 1, 2),
+		Protocol: layers.IPProtocolTCP,
+	}
+	tcp := layers.TCP{SrcPort: 1234, DstPort: 80, SYN: true}
+
+	err := tcp.SetNetworkLayerForChecksum(&ip4)
+	if err != nil {
+		t.Fatalf("set network layer for checksum: %v", err)
+	}
+
+	buf := gopacket.NewSerializeBuffer()
+	opts := gopacket.SerializeOptions{ComputeChecksums: true, FixLengths: true}
+
+	err = gopacket.SerializeLayers(buf, opts, &eth, &dot1q, &ip4, &tcp)
+	if err != nil {
+		t.Fatalf("serialize layers: %v", err)
+	}
+
+	packet := gopacket.NewPacket(buf.Bytes(), layers.LayerTypeEthernet, gopacket.Default)
+
+	indicator, err := ParsePacket(packet)
+	if err != nil {
+		t.Fatalf("parse packet: %v", err)
+	}
+
+	vlan := indicator.VLAN()
+	if len(vlan) != 1 || vlan[0] != 42 {
+		t.Fatalf("VLAN() = %v, want [42]", vlan)
+	}
+	if indicator.TCPLayer() == nil {
+		t.Fatalf("expected TCP layer to be resolved through the 802.1Q tag")
+	}
+}
+
+func TestParsePacket_QinQ(t *testing.T) {
+	eth := layers.Ethernet{
+		SrcMAC:       net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x55},
+		DstMAC:       net.HardwareAddr{0x00, 0x66, 0x77, 0x88, 0x99, 0xaa},
+		EthernetType: layers.EthernetTypeQinQ,
+	}
+	outer := layers.Dot1Q{
+		VLANIdentifier: 100,
+		Type:           layers.EthernetTypeDot1Q,
+	}
+	inner := layers.Dot1Q{
+		VLANIdentifier: 200,
+		Type:           layers.EthernetTypeIPv4,
+	}
+	ip4 := layers.IPv4{
+		Version:  4,
+		TTL:      64,
+		SrcIP:    net.IPv4(10, 0, 0, 1),
+		DstIP:    net.IPv4(10, 0, 0, 2),
+		Protocol: layers.IPProtocolUDP,
+	}
+	udp := layers.UDP{SrcPort: 1111, DstPort: 2222}
+
+	err := udp.SetNetworkLayerForChecksum(&ip4)
+	if err != nil {
+		t.Fatalf("set network layer for checksum: %v", err)
+	}
+
+	buf := gopacket.NewSerializeBuffer()
+	opts := gopacket.SerializeOptions{ComputeChecksums: true, FixLengths: true}
+
+	err = gopacket.SerializeLayers(buf, opts, &eth, &outer, &inner, &ip4, &udp)
+	if err != nil {
+		t.Fatalf("serialize layers: %v", err)
+	}
+
+	packet := gopacket.NewPacket(buf.Bytes(), layers.LayerTypeEthernet, gopacket.Default)
+
+	indicator, err := ParsePacket(packet)
+	if err != nil {
+		t.Fatalf("parse packet: %v", err)
+	}
+
+	vlan := indicator.VLAN()
+	if len(vlan) != 2 || vlan[0] != 100 || vlan[1] != 200 {
+		t.Fatalf("VLAN() = %v, want [100 200]", vlan)
+	}
+	if indicator.UDPLayer() == nil {
+		t.Fatalf("expected UDP layer to be resolved through both 802.1Q tags")
+	}
+}
+
+func TestBuildVLANLayers_RoundTrips(t *testing.T) {
+	eth := layers.Ethernet{
+		SrcMAC:       net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x55},
+		DstMAC:       net.HardwareAddr{0x00, 0x66, 0x77, 0x88, 0x99, 0xaa},
+		EthernetType: layers.EthernetTypeQinQ,
+	}
+	outer := layers.Dot1Q{VLANIdentifier: 100, Type: layers.EthernetTypeDot1Q}
+	inner := layers.Dot1Q{VLANIdentifier: 200, Type: layers.EthernetTypeIPv4}
+	ip4 := layers.IPv4{
+		Version:  4,
+		TTL:      64,
+		SrcIP:    net.IPv4(10, 0, 0, 1),
+		DstIP:    net.IPv4(10, 0, 0, 2),
+		Protocol: layers.IPProtocolUDP,
+	}
+	udp := layers.UDP{SrcPort: 1111, DstPort: 2222}
+
+	err := udp.SetNetworkLayerForChecksum(&ip4)
+	if err != nil {
+		t.Fatalf("set network layer for checksum: %v", err)
+	}
+
+	buf := gopacket.NewSerializeBuffer()
+	opts := gopacket.SerializeOptions{ComputeChecksums: true, FixLengths: true}
+
+	err = gopacket.SerializeLayers(buf, opts, &eth, &outer, &inner, &ip4, &udp)
+	if err != nil {
+		t.Fatalf("serialize layers: %v", err)
+	}
+
+	packet := gopacket.NewPacket(buf.Bytes(), layers.LayerTypeEthernet, gopacket.Default)
+
+	indicator, err := ParsePacket(packet)
+	if err != nil {
+		t.Fatalf("parse packet: %v", err)
+	}
+
+	// Re-wrap a NAT-rewritten IPv4 datagram in the same VLAN tags the original frame
+	// arrived with, as egress would need to after rewriting indicator's IP/ports.
+	tags := BuildVLANLayers(indicator, layers.EthernetTypeIPv4)
+	if len(tags) != 2 {
+		t.Fatalf("BuildVLANLayers returned %d tags, want 2", len(tags))
+	}
+
+	rewrittenEth := layers.Ethernet{
+		SrcMAC:       eth.SrcMAC,
+		DstMAC:       eth.DstMAC,
+		EthernetType: layers.EthernetTypeQinQ,
+	}
+	rewrittenIP4 := layers.IPv4{
+		Version:  4,
+		TTL:      64,
+		SrcIP:    net.IPv4(192, 168, 1, 1),
+		DstIP:    net.IPv4(192, 168, 1, 2),
+		Protocol: layers.IPProtocolUDP,
+	}
+	rewrittenUDP := layers.UDP{SrcPort: 3333, DstPort: 4444}
+
+	err = rewrittenUDP.SetNetworkLayerForChecksum(&rewrittenIP4)
+	if err != nil {
+		t.Fatalf("set network layer for checksum: %v", err)
+	}
+
+	egressLayers := append([]gopacket.SerializableLayer{&rewrittenEth}, tags...)
+	egressLayers = append(egressLayers, &rewrittenIP4, &rewrittenUDP)
+
+	egressBuf := gopacket.NewSerializeBuffer()
+
+	err = gopacket.SerializeLayers(egressBuf, opts, egressLayers...)
+	if err != nil {
+		t.Fatalf("serialize egress layers: %v", err)
+	}
+
+	egressPacket := gopacket.NewPacket(egressBuf.Bytes(), layers.LayerTypeEthernet, gopacket.Default)
+
+	egressIndicator, err := ParsePacket(egressPacket)
+	if err != nil {
+		t.Fatalf("parse egress packet: %v", err)
+	}
+
+	vlan := egressIndicator.VLAN()
+	if len(vlan) != 2 || vlan[0] != 100 || vlan[1] != 200 {
+		t.Fatalf("egress VLAN() = %v, want [100 200]", vlan)
+	}
+}
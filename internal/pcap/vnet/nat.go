@@ -0,0 +1,119 @@
+package vnet
+
+import (
+	"net"
+	"sync"
+)
+
+// Behavior describes how a simulated NAT gateway chooses the external port for an outbound
+// flow, and which inbound packets it lets back in.
+type Behavior int
+
+const (
+	// FullCone maps an internal (IP, port) to a single external port regardless of destination,
+	// and lets any external host reach that port.
+	FullCone Behavior = iota
+	// RestrictedCone maps an internal (IP, port) to a single external port, but only accepts
+	// inbound packets from an external IP the internal host has already sent to.
+	RestrictedCone
+	// PortRestrictedCone is like RestrictedCone, additionally restricting by external port.
+	PortRestrictedCone
+	// Symmetric maps each distinct (internal (IP, port), destination) pair to its own external
+	// port, so the same internal flow talking to two destinations is seen externally as two
+	// unrelated flows, each only reachable from the destination it was opened to.
+	Symmetric
+)
+
+// NAT simulates the port-allocation and inbound-filtering behavior of a NAT gateway sitting
+// between a VNet segment and the outside world, without actually rewriting or relaying any
+// packets. It lets tests assert NAT-behavior invariants over (internal, destination) address
+// pairs; it does not use pcap.NATGuide, which keys the real NAT map by source and protocol only.
+type NAT struct {
+	Behavior Behavior
+
+	mu       sync.Mutex
+	portOf   map[string]int          // mapping key (scoped per Behavior) -> external port
+	allowed  map[int]map[string]bool // external port -> set of allowed remote scopes
+	nextPort int
+}
+
+// NewNAT returns an empty NAT simulating the given behavior. Allocated external ports start at
+// firstPort and increase by one per new mapping.
+func NewNAT(behavior Behavior, firstPort int) *NAT {
+	return &NAT{
+		Behavior: behavior,
+		portOf:   make(map[string]int),
+		allowed:  make(map[int]map[string]bool),
+		nextPort: firstPort,
+	}
+}
+
+// ExternalPort returns the external port a packet from internal to destination is mapped to,
+// allocating a new one the first time a given flow, as scoped by Behavior, is seen. For every
+// behavior but Symmetric, the same internal (IP, port) always maps to the same external port
+// regardless of destination; Symmetric allocates a distinct port per destination.
+func (n *NAT) ExternalPort(internal, destination net.Addr) int {
+	key := internal.String()
+	if n.Behavior == Symmetric {
+		key += "->" + destination.String()
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	port, ok := n.portOf[key]
+	if !ok {
+		port = n.nextPort
+		n.nextPort++
+		n.portOf[key] = port
+		n.allowed[port] = make(map[string]bool)
+	}
+
+	n.allowed[port][n.inboundScope(destination)] = true
+
+	return port
+}
+
+// inboundScope returns the remote-endpoint granularity Behavior restricts inbound packets to.
+func (n *NAT) inboundScope(remote net.Addr) string {
+	switch n.Behavior {
+	case FullCone:
+		return "*"
+	case RestrictedCone:
+		ip, _ := splitHostPort(remote)
+
+		return ip
+	default: // PortRestrictedCone, Symmetric
+		ip, port := splitHostPort(remote)
+
+		return ip + ":" + port
+	}
+}
+
+// Allows reports whether an inbound packet from "from" addressed to the given externally
+// mapped port would be let through, based on the outbound flows already observed via
+// ExternalPort.
+func (n *NAT) Allows(port int, from net.Addr) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	scopes, ok := n.allowed[port]
+	if !ok {
+		return false
+	}
+
+	if n.Behavior == FullCone {
+		return scopes["*"]
+	}
+
+	return scopes[n.inboundScope(from)]
+}
+
+func splitHostPort(a net.Addr) (ip string, port string) {
+	host, p, err := net.SplitHostPort(a.String())
+	if err != nil {
+		return a.String(), ""
+	}
+
+	return host, p
+}
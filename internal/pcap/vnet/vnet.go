@@ -0,0 +1,163 @@
+// Package vnet provides an in-process virtual L2 segment for exercising pcap's packet parsing
+// and NAT logic without a live interface, inspired by Tailscale's natlab vnet.
+package vnet
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// Node is a virtual host on a VNet segment.
+type Node struct {
+	MAC net.HardwareAddr
+	IP  net.IP
+}
+
+// VConn is a virtual connection bound to a Node on a VNet segment. It exposes the same narrow
+// read/write surface RawConn exposes to the rest of pcap (ReadPacketData/WritePacketData), so
+// code written against a live capture can be driven deterministically in tests without change.
+type VConn struct {
+	node *Node
+	vnet *VNet
+	in   chan []byte
+}
+
+// ReadPacketData blocks until a packet addressed to this node's MAC or IP is delivered, or the
+// connection is closed with Close.
+func (c *VConn) ReadPacketData() ([]byte, error) {
+	data, ok := <-c.in
+	if !ok {
+		return nil, errors.New("vnet: connection closed")
+	}
+
+	return data, nil
+}
+
+// WritePacketData sends a fully serialized link layer frame onto the segment.
+func (c *VConn) WritePacketData(data []byte) error {
+	return c.vnet.deliver(c.node, data)
+}
+
+// Close detaches the connection from its VNet. Reads already blocked on it return an error.
+func (c *VConn) Close() error {
+	c.vnet.removeNode(c.node)
+
+	return nil
+}
+
+// VNet is a simulated Ethernet segment that relays serialized frames between registered Nodes,
+// keyed by destination MAC or, failing that, destination IP. It lets pcap.ParsePacket,
+// pcap.Reassembler and the NAT map keyed by pcap.NATGuide be exercised against real, fully
+// serialized packets instead of a live pcap.OpenLive handle.
+type VNet struct {
+	mu    sync.Mutex
+	conns map[string]*VConn // keyed by Node.IP.String()
+	macs  map[string]*VConn // keyed by Node.MAC.String()
+}
+
+// NewVNet returns an empty VNet segment.
+func NewVNet() *VNet {
+	return &VNet{
+		conns: make(map[string]*VConn),
+		macs:  make(map[string]*VConn),
+	}
+}
+
+// AddNode registers node on the segment and returns a VConn bound to it.
+func (v *VNet) AddNode(node *Node) *VConn {
+	conn := &VConn{node: node, vnet: v, in: make(chan []byte, 64)}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.conns[node.IP.String()] = conn
+	v.macs[node.MAC.String()] = conn
+
+	return conn
+}
+
+func (v *VNet) removeNode(node *Node) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	conn, ok := v.macs[node.MAC.String()]
+
+	delete(v.conns, node.IP.String())
+	delete(v.macs, node.MAC.String())
+
+	// Closing the channel wakes any reader blocked in ReadPacketData with an error.
+	if ok && conn.node == node {
+		close(conn.in)
+	}
+}
+
+// deliver routes a serialized Ethernet frame written by src to whichever registered node its
+// destination MAC, or failing that destination IP, resolves to.
+func (v *VNet) deliver(src *Node, data []byte) error {
+	packet := gopacket.NewPacket(data, layers.LayerTypeEthernet, gopacket.Default)
+
+	ethernetLayer := packet.Layer(layers.LayerTypeEthernet)
+	if ethernetLayer == nil {
+		return errors.New("vnet: missing ethernet layer")
+	}
+	ethernet := ethernetLayer.(*layers.Ethernet)
+
+	// The lock is held through the non-blocking send so removeNode cannot close dst.in under it.
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	dst, ok := v.macs[ethernet.DstMAC.String()]
+	if !ok {
+		if networkLayer := packet.NetworkLayer(); networkLayer != nil {
+			dst, ok = v.conns[dstIP(networkLayer).String()]
+		}
+	}
+
+	if !ok {
+		return fmt.Errorf("vnet: no node for destination %s", ethernet.DstMAC)
+	}
+
+	select {
+	case dst.in <- data:
+		return nil
+	default:
+		return fmt.Errorf("vnet: receive buffer full for node %s", dst.node.IP)
+	}
+}
+
+func dstIP(networkLayer gopacket.NetworkLayer) net.IP {
+	switch l := networkLayer.(type) {
+	case *layers.IPv4:
+		return l.DstIP
+	case *layers.IPv6:
+		return l.DstIP
+	default:
+		return nil
+	}
+}
+
+// BuildEthernetIPv4 serializes an Ethernet+IPv4 frame with rest (typically a transport layer
+// optionally followed by a gopacket.Payload) stacked on top, filling in lengths and checksums.
+// Any SerializableLayer in rest that needs the IPv4 layer for a pseudo-header checksum (TCP,
+// UDP) must already have had SetNetworkLayerForChecksum(ip4) called on it.
+func BuildEthernetIPv4(eth *layers.Ethernet, ip4 *layers.IPv4, rest ...gopacket.SerializableLayer) ([]byte, error) {
+	buf := gopacket.NewSerializeBuffer()
+	opts := gopacket.SerializeOptions{ComputeChecksums: true, FixLengths: true}
+
+	layerList := append([]gopacket.SerializableLayer{eth, ip4}, rest...)
+
+	err := gopacket.SerializeLayers(buf, opts, layerList...)
+	if err != nil {
+		return nil, fmt.Errorf("serialize layers: %w", err)
+	}
+
+	out := make([]byte, len(buf.Bytes()))
+	copy(out, buf.Bytes())
+
+	return out, nil
+}
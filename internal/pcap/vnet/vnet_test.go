@@ -0,0 +1,205 @@
+package vnet
+
+import (
+	"net"
+	"testing"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+
+	"ikago/internal/pcap"
+)
+
+func TestVNet_DeliversSerializedPacketToDestinationNode(t *testing.T) {
+	v := NewVNet()
+
+	a := &Node{MAC: net.HardwareAddr{0, 1, 2, 3, 4, 5}, IP: net.IPv4(192, 168, 1, 1)}
+	b := &Node{MAC: net.HardwareAddr{0, 1, 2, 3, 4, 6}, IP: net.IPv4(192, 168, 1, 2)}
+
+	connA := v.AddNode(a)
+	connB := v.AddNode(b)
+
+	eth := layers.Ethernet{SrcMAC: a.MAC, DstMAC: b.MAC, EthernetType: layers.EthernetTypeIPv4}
+	ip4 := layers.IPv4{Version: 4, TTL: 64, SrcIP: a.IP, DstIP: b.IP, Protocol: layers.IPProtocolUDP}
+	udp := layers.UDP{SrcPort: 1000, DstPort: 2000}
+
+	err := udp.SetNetworkLayerForChecksum(&ip4)
+	if err != nil {
+		t.Fatalf("set network layer for checksum: %v", err)
+	}
+
+	frame, err := BuildEthernetIPv4(&eth, &ip4, &udp, gopacket.Payload("hello"))
+	if err != nil {
+		t.Fatalf("build frame: %v", err)
+	}
+
+	if err := connA.WritePacketData(frame); err != nil {
+		t.Fatalf("write packet data: %v", err)
+	}
+
+	data, err := connB.ReadPacketData()
+	if err != nil {
+		t.Fatalf("read packet data: %v", err)
+	}
+
+	rawPacket, err := pcap.ParseRawPacket(data)
+	if err != nil {
+		t.Fatalf("parse raw packet: %v", err)
+	}
+
+	indicator, err := pcap.ParsePacket(rawPacket)
+	if err != nil {
+		t.Fatalf("parse packet: %v", err)
+	}
+
+	if !indicator.SrcIP().Equal(a.IP) || !indicator.DstIP().Equal(b.IP) {
+		t.Fatalf("unexpected src/dst: %s -> %s", indicator.SrcIP(), indicator.DstIP())
+	}
+	if string(indicator.Payload()) != "hello" {
+		t.Fatalf("payload = %q, want %q", indicator.Payload(), "hello")
+	}
+}
+
+func TestNAT_SymmetricAllocatesDistinctPortsPerDestination(t *testing.T) {
+	nat := NewNAT(Symmetric, 40000)
+
+	internal := &net.UDPAddr{IP: net.IPv4(192, 168, 1, 1), Port: 5000}
+	dst1 := &net.UDPAddr{IP: net.IPv4(8, 8, 8, 8), Port: 53}
+	dst2 := &net.UDPAddr{IP: net.IPv4(1, 1, 1, 1), Port: 53}
+
+	port1 := nat.ExternalPort(internal, dst1)
+	port2 := nat.ExternalPort(internal, dst2)
+
+	if port1 == port2 {
+		t.Fatalf("symmetric NAT must map the same internal flow to distinct external ports for distinct destinations, got %d for both", port1)
+	}
+
+	// Revisiting dst1 must reuse the same mapping.
+	if again := nat.ExternalPort(internal, dst1); again != port1 {
+		t.Fatalf("re-sending to dst1 got port %d, want the original %d", again, port1)
+	}
+
+	if !nat.Allows(port1, dst1) {
+		t.Fatalf("symmetric NAT should allow a reply from the destination that opened the mapping")
+	}
+	if nat.Allows(port1, dst2) {
+		t.Fatalf("symmetric NAT must not allow a reply from a destination that did not open the mapping")
+	}
+}
+
+func TestNAT_FullConeReusesPortAndAllowsAnyone(t *testing.T) {
+	nat := NewNAT(FullCone, 40000)
+
+	internal := &net.UDPAddr{IP: net.IPv4(192, 168, 1, 1), Port: 5000}
+	dst1 := &net.UDPAddr{IP: net.IPv4(8, 8, 8, 8), Port: 53}
+	dst2 := &net.UDPAddr{IP: net.IPv4(1, 1, 1, 1), Port: 443}
+
+	port1 := nat.ExternalPort(internal, dst1)
+	port2 := nat.ExternalPort(internal, dst2)
+
+	if port1 != port2 {
+		t.Fatalf("full cone NAT must reuse the same external port across destinations, got %d and %d", port1, port2)
+	}
+
+	stranger := &net.UDPAddr{IP: net.IPv4(9, 9, 9, 9), Port: 12345}
+	if !nat.Allows(port1, stranger) {
+		t.Fatalf("full cone NAT should allow inbound packets from any host")
+	}
+}
+
+func TestNAT_RestrictedConeFiltersByHostButNotPort(t *testing.T) {
+	nat := NewNAT(RestrictedCone, 40000)
+
+	internal := &net.UDPAddr{IP: net.IPv4(192, 168, 1, 1), Port: 5000}
+	dst := &net.UDPAddr{IP: net.IPv4(8, 8, 8, 8), Port: 53}
+
+	port := nat.ExternalPort(internal, dst)
+
+	sameHostDifferentPort := &net.UDPAddr{IP: net.IPv4(8, 8, 8, 8), Port: 9999}
+	if !nat.Allows(port, sameHostDifferentPort) {
+		t.Fatalf("restricted cone NAT should allow any port from a host already contacted")
+	}
+
+	otherHost := &net.UDPAddr{IP: net.IPv4(1, 1, 1, 1), Port: 53}
+	if nat.Allows(port, otherHost) {
+		t.Fatalf("restricted cone NAT must not allow a host that was never contacted")
+	}
+}
+
+func TestVNet_ReassemblesFragmentsDeliveredAcrossTheSegment(t *testing.T) {
+	v := NewVNet()
+
+	a := &Node{MAC: net.HardwareAddr{0, 1, 2, 3, 4, 7}, IP: net.IPv4(192, 168, 2, 1)}
+	b := &Node{MAC: net.HardwareAddr{0, 1, 2, 3, 4, 8}, IP: net.IPv4(192, 168, 2, 2)}
+
+	connA := v.AddNode(a)
+	connB := v.AddNode(b)
+
+	r := pcap.NewReassembler()
+
+	first := make([]byte, 8)
+	for i := range first {
+		first[i] = byte(i)
+	}
+	second := []byte{0xde, 0xad}
+
+	for i, piece := range [][]byte{first, second} {
+		flags := layers.IPv4MoreFragments
+		offset := uint16(0)
+		if i == 1 {
+			flags = 0
+			offset = 1
+		}
+
+		eth := layers.Ethernet{SrcMAC: a.MAC, DstMAC: b.MAC, EthernetType: layers.EthernetTypeIPv4}
+		ip4 := layers.IPv4{
+			Version: 4, TTL: 64, Id: 7,
+			Flags: flags, FragOffset: offset,
+			SrcIP: a.IP, DstIP: b.IP, Protocol: layers.IPProtocolUDP,
+		}
+
+		frame, err := BuildEthernetIPv4(&eth, &ip4, gopacket.Payload(piece))
+		if err != nil {
+			t.Fatalf("build fragment %d: %v", i, err)
+		}
+
+		if err := connA.WritePacketData(frame); err != nil {
+			t.Fatalf("write fragment %d: %v", i, err)
+		}
+	}
+
+	var reassembled *pcap.PacketIndicator
+	for i := 0; i < 2; i++ {
+		data, err := connB.ReadPacketData()
+		if err != nil {
+			t.Fatalf("read fragment %d: %v", i, err)
+		}
+
+		rawPacket, err := pcap.ParseRawPacket(data)
+		if err != nil {
+			t.Fatalf("parse raw packet %d: %v", i, err)
+		}
+
+		indicator, err := pcap.ParsePacket(rawPacket)
+		if err != nil {
+			t.Fatalf("parse packet %d: %v", i, err)
+		}
+
+		result, ok, err := r.Add(indicator)
+		if err != nil {
+			t.Fatalf("add fragment %d: %v", i, err)
+		}
+		if ok {
+			reassembled = result
+		}
+	}
+
+	if reassembled == nil {
+		t.Fatalf("expected the flow to be reassembled after both fragments were delivered")
+	}
+
+	want := append(append([]byte{}, first...), second...)
+	if got := reassembled.NetworkPayload(); string(got) != string(want) {
+		t.Fatalf("reassembled payload = %x, want %x", got, want)
+	}
+}